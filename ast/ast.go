@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/nayyara-airlangga/basedlang/token"
@@ -54,19 +56,28 @@ func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
 func (i *Identifier) String() string       { return i.Value }
 
+// LetStatement binds Value to Name, or destructures it with Pattern when the
+// let starts with [ or {. A const statement is a LetStatement whose bindings
+// can't be redeclared.
 type LetStatement struct {
-	Token token.Token // token.LET
-	Name  *Identifier
-	Value Expression
+	Token   token.Token // token.LET or token.CONST
+	Name    *Identifier
+	Pattern Pattern // nil unless the let destructures
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode()       {}
+func (ls *LetStatement) IsConst() bool        { return ls.Token.Type == token.CONST }
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -78,6 +89,67 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ImportStatement binds the module at Path to Alias.
+type ImportStatement struct {
+	Token token.Token // token.IMPORT
+	Path  string
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + strconv.Quote(is.Path) + " as " + is.Alias.String() + ";"
+}
+
+// ExportStatement is a let statement or function declaration whose binding is
+// visible to importers of the module.
+type ExportStatement struct {
+	Token token.Token // token.EXPORT
+	Let   *LetStatement
+	Fn    *FunctionStatement // set instead of Let for export fn
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string {
+	if es.Fn != nil {
+		return es.TokenLiteral() + " " + es.Fn.String()
+	}
+	return es.TokenLiteral() + " " + es.Let.String()
+}
+
+// FunctionStatement declares a named function in the current scope.
+type FunctionStatement struct {
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Function.TokenLiteral() }
+func (fs *FunctionStatement) String() string       { return fs.Function.String() }
+
+// StructStatement declares a struct type with named fields.
+type StructStatement struct {
+	Token  token.Token // token.STRUCT
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	if len(ss.Fields) == 0 {
+		return ss.TokenLiteral() + " " + ss.Name.String() + " {}"
+	}
+
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ss.TokenLiteral() + " " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
 type ReturnStatement struct {
 	Token       token.Token // token.RETURN
 	ReturnValue Expression
@@ -97,6 +169,25 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+type ThrowStatement struct {
+	Token token.Token // token.THROW
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ts.TokenLiteral() + " ")
+	if ts.Value != nil {
+		out.WriteString(ts.Value.String())
+	}
+	out.WriteString(";")
+
+	return out.String()
+}
+
 type ExpressionStatement struct {
 	Token      token.Token
 	Expression Expression
@@ -160,6 +251,25 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+type PostfixExpression struct {
+	Token    token.Token
+	Left     Expression
+	Operator string
+}
+
+func (pe *PostfixExpression) expressionNode()      {}
+func (pe *PostfixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type BooleanLiteral struct {
 	Token token.Token
 	Value bool
@@ -194,6 +304,34 @@ func (ife *IfExpression) String() string {
 	return out.String()
 }
 
+type TryExpression struct {
+	Token      token.Token // token.TRY
+	Body       *BlockStatement
+	CatchParam *Identifier // nil without a catch clause
+	Catch      *BlockStatement
+	Finally    *BlockStatement
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+
+	if te.Catch != nil {
+		out.WriteString(" catch (" + te.CatchParam.String() + ") ")
+		out.WriteString(te.Catch.String())
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally ")
+		out.WriteString(te.Finally.String())
+	}
+
+	return out.String()
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
@@ -212,9 +350,27 @@ func (bs *BlockStatement) String() string {
 	return out.String()
 }
 
+// Parameter is a function parameter. Default is evaluated when the call
+// leaves the parameter out.
+type Parameter struct {
+	Pattern Pattern
+	Default Expression // nil for a required parameter
+}
+
+func (p *Parameter) String() string {
+	if p.Default != nil {
+		return p.Pattern.String() + " = " + p.Default.String()
+	}
+	return p.Pattern.String()
+}
+
+// FunctionLiteral is fn(params) { body }. A named literal, fn name(params)
+// { body }, can call itself by its name.
 type FunctionLiteral struct {
 	Token  token.Token
-	Params []*Identifier
+	Name   *Identifier // nil for an anonymous function
+	Params []*Parameter
+	Rest   Pattern // collects the remaining arguments, nil without ...rest
 	Body   *BlockStatement
 }
 
@@ -227,8 +383,14 @@ func (fl *FunctionLiteral) String() string {
 	for _, p := range fl.Params {
 		params = append(params, p.String())
 	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
+	}
 
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" " + fl.Name.String())
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -237,6 +399,26 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// SpreadExpression passes the elements of an array as separate arguments.
+type SpreadExpression struct {
+	Token token.Token // token.ELLIPSIS
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode()      {}
+func (se *SpreadExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpreadExpression) String() string       { return "..." + se.Value.String() }
+
+// NamedArgument passes Value to the parameter called Name.
+type NamedArgument struct {
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Name.TokenLiteral() }
+func (na *NamedArgument) String() string       { return na.Name.String() + ": " + na.Value.String() }
+
 type CallExpression struct {
 	Token    token.Token
 	Function Expression
@@ -272,6 +454,18 @@ func (s *StringLiteral) expressionNode()      {}
 func (s *StringLiteral) TokenLiteral() string { return s.Token.Literal }
 func (s *StringLiteral) String() string       { return s.TokenLiteral() }
 
+// RegexLiteral is #/pattern/, compiled when it is parsed.
+type RegexLiteral struct {
+	Token  token.Token
+	Regexp *regexp.Regexp
+}
+
+func (r *RegexLiteral) expressionNode()      {}
+func (r *RegexLiteral) TokenLiteral() string { return r.Token.Literal }
+func (r *RegexLiteral) String() string {
+	return "#/" + strings.ReplaceAll(r.Token.Literal, "/", `\/`) + "/"
+}
+
 type ArrayLiteral struct {
 	Token token.Token
 	Elems []Expression
@@ -296,10 +490,93 @@ func (a *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// SliceExpression is xs[start:end:step]. Omitted parts are nil.
+type SliceExpression struct {
+	Token    token.Token
+	Left     Expression
+	Start    Expression
+	End      Expression
+	Step     Expression
+	Optional bool // left?.[start:end:step]
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?.")
+	}
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":")
+		out.WriteString(se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// ConditionalExpression is cond ? consequence : alternative.
+type ConditionalExpression struct {
+	Token       token.Token // token.TERNARY
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (ce *ConditionalExpression) expressionNode()      {}
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
+
+// AssignExpression is target = value. Only members and indexes can be
+// assigned to.
+type AssignExpression struct {
+	Token  token.Token // token.ASSIGN
+	Target Expression
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " = " + ae.Value.String() + ")"
+}
+
+// MemberExpression is obj.property.
+type MemberExpression struct {
+	Token    token.Token // token.DOT or token.OPTIONAL
+	Object   Expression
+	Property *Identifier
+	Optional bool // obj?.property
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	if me.Optional {
+		return "(" + me.Object.String() + "?." + me.Property.String() + ")"
+	}
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
 type IndexExpression struct {
-	Token token.Token
-	Left  Expression
-	Index Expression
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool // left?.[index]
 }
 
 func (ie *IndexExpression) expressionNode()      {}
@@ -309,9 +586,161 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
 
 	return out.String()
 }
+
+// Pattern is matched against a value, binding names on success. Patterns are
+// shared by match arms, let statements and function parameters.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// PatternNames returns the names p binds, in order.
+func PatternNames(p Pattern) []string {
+	switch p := p.(type) {
+	case *BindingPattern:
+		return []string{p.Name.Value}
+	case *ArrayPattern:
+		names := []string{}
+		for _, el := range p.Elems {
+			names = append(names, PatternNames(el)...)
+		}
+		if p.Rest != nil {
+			names = append(names, PatternNames(p.Rest)...)
+		}
+		return names
+	case *HashPattern:
+		names := []string{}
+		for _, pair := range p.Pairs {
+			names = append(names, PatternNames(pair.Value)...)
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+// WildcardPattern is _, which matches anything and binds nothing.
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// BindingPattern matches anything and binds it to Name.
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// LiteralPattern matches values equal to a literal.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string {
+	if str, isStr := lp.Value.(*StringLiteral); isStr {
+		return strconv.Quote(str.Value)
+	}
+	return lp.Value.String()
+}
+
+// ArrayPattern matches arrays element by element. Without Rest the lengths
+// must be equal, with it the remaining elements are matched against Rest as
+// an array.
+type ArrayPattern struct {
+	Token token.Token // token.LBRACKET
+	Elems []Pattern
+	Rest  Pattern
+}
+
+func (ap *ArrayPattern) patternNode()         {}
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+func (ap *ArrayPattern) String() string {
+	elems := []string{}
+	for _, el := range ap.Elems {
+		elems = append(elems, el.String())
+	}
+	if ap.Rest != nil {
+		elems = append(elems, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+type HashPatternPair struct {
+	Key   string
+	Value Pattern
+}
+
+// HashPattern matches hashes and structs that have every key of the pattern.
+// Other keys are ignored.
+type HashPattern struct {
+	Token token.Token // token.LBRACE
+	Pairs []*HashPatternPair
+}
+
+func (hp *HashPattern) patternNode()         {}
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for _, pair := range hp.Pairs {
+		if binding, isBinding := pair.Value.(*BindingPattern); isBinding && binding.Name.Value == pair.Key {
+			pairs = append(pairs, pair.Key)
+		} else {
+			pairs = append(pairs, strconv.Quote(pair.Key)+": "+pair.Value.String())
+		}
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+type MatchArm struct {
+	Pattern Pattern
+	Guard   Expression // nil without an if guard
+	Body    Expression // an expression or a *BlockStatement
+}
+
+// MatchExpression evaluates the body of the first arm whose pattern matches
+// Subject and whose guard holds.
+type MatchExpression struct {
+	Token   token.Token // token.MATCH
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match (" + me.Subject.String() + ") { ")
+	for i, arm := range me.Arms {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(arm.Pattern.String())
+		if arm.Guard != nil {
+			out.WriteString(" if " + arm.Guard.String())
+		}
+		out.WriteString(" => " + arm.Body.String())
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
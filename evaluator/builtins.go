@@ -1,47 +1,146 @@
 package evaluator
 
-import "github.com/nayyara-airlangga/basedlang/object"
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
 
 const (
 	ErrInvalidLen                  = "invalid argument: %s (%s) not supported for len"
 	ErrNotEnoughArgsAppend         = "invalid argument: not enough arguments for append, expected>=1, got=0"
 	ErrFirstArgShouldBeArrayAppend = "invalid argument: first argument for append must be an array. got=%s (%s)"
+	ErrWrongNumberOfArgsError      = "wrong number of arguments. got=%d, want=2 or 3"
+	ErrInvalidArgError             = "invalid argument: %s for error must be a %s. got=%s (%s)"
 )
 
 var builtins map[string]*object.Builtin = map[string]*object.Builtin{
 	"len": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(c object.CallContext, args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError(ErrWrongNumberOfArgs, len(args), 1)
+				return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgs, len(args), 1)
 			}
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elems))}
+			case *object.Hash:
+				return &object.Integer{Value: int64(len(arg.Keys))}
 			default:
-				return newError(ErrInvalidLen, arg.Inspect(), arg.Type())
+				return newError(object.ARGUMENT_ERROR, ErrInvalidLen, arg.Inspect(), arg.Type())
+			}
+		},
+	},
+	"error": {
+		Fn: func(c object.CallContext, args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsError, len(args))
+			}
+
+			kind, isStr := args[0].(*object.String)
+			if !isStr {
+				return newError(object.ARGUMENT_ERROR, ErrInvalidArgError, "kind", object.STRING, args[0].Inspect(), args[0].Type())
+			}
+			msg, isStr := args[1].(*object.String)
+			if !isStr {
+				return newError(object.ARGUMENT_ERROR, ErrInvalidArgError, "message", object.STRING, args[1].Inspect(), args[1].Type())
+			}
+
+			err := &object.Error{Kind: object.ErrorKind(kind.Value), Message: msg.Value}
+			if len(args) == 3 {
+				cause, isErr := args[2].(*object.Error)
+				if !isErr {
+					return newError(object.ARGUMENT_ERROR, ErrInvalidArgError, "cause", object.ERROR, args[2].Inspect(), args[2].Type())
+				}
+				err.Cause = cause
 			}
+
+			return err
+		},
+	},
+	"is_error": {
+		Fn: func(c object.CallContext, args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgs, len(args), 1)
+			}
+			return nativeBoolToObjBool(args[0].Type() == object.ERROR)
 		},
 	},
 	"append": {
-		Fn: func(args ...object.Object) object.Object {
+		Fn: func(c object.CallContext, args ...object.Object) object.Object {
 			if len(args) < 1 {
-				return newError(ErrNotEnoughArgsAppend)
+				return newError(object.ARGUMENT_ERROR, ErrNotEnoughArgsAppend)
 			}
 
 			arr, isArr := args[0].(*object.Array)
 			if !isArr {
-				return newError(ErrFirstArgShouldBeArrayAppend, args[0].Inspect(), args[0].Type())
-			}
-			if len(args) == 1 {
-				return arr
+				return newError(object.ARGUMENT_ERROR, ErrFirstArgShouldBeArrayAppend, args[0].Inspect(), args[0].Type())
 			}
 
-			newArr := &object.Array{Elems: append(arr.Elems, args[1:]...)}
+			// Copy so the new array doesn't share storage with arr, which may be
+			// frozen or appended to again
+			newArr := &object.Array{Elems: append(slices.Clone(arr.Elems), args[1:]...)}
 
 			return newArr
 		},
 	},
+	"map":         {Fn: builtinMap},
+	"filter":      {Fn: builtinFilter},
+	"reduce":      {Fn: builtinReduce},
+	"each":        {Fn: builtinEach},
+	"find":        {Fn: builtinFind},
+	"any":         {Fn: builtinAny},
+	"all":         {Fn: builtinAll},
+	"sort":        {Fn: builtinSort},
+	"zip":         {Fn: builtinZip},
+	"flat_map":    {Fn: builtinFlatMap},
+	"keys":        {Fn: builtinKeys},
+	"values":      {Fn: builtinValues},
+	"has":         {Fn: builtinHas},
+	"freeze":      {Fn: builtinFreeze},
+	"is_frozen":   {Fn: builtinIsFrozen},
+	"split":       {Fn: builtinSplit},
+	"join":        {Fn: builtinJoin},
+	"trim":        {Fn: stringFn("trim", strings.TrimSpace)},
+	"trim_left":   {Fn: stringFn("trim_left", func(s string) string { return strings.TrimLeftFunc(s, unicode.IsSpace) })},
+	"trim_right":  {Fn: stringFn("trim_right", func(s string) string { return strings.TrimRightFunc(s, unicode.IsSpace) })},
+	"upper":       {Fn: stringFn("upper", strings.ToUpper)},
+	"lower":       {Fn: stringFn("lower", strings.ToLower)},
+	"contains":    {Fn: stringPredicate("contains", strings.Contains)},
+	"starts_with": {Fn: stringPredicate("starts_with", strings.HasPrefix)},
+	"ends_with":   {Fn: stringPredicate("ends_with", strings.HasSuffix)},
+	"index_of":    {Fn: builtinIndexOf},
+	"replace":     {Fn: builtinReplace},
+	"repeat":      {Fn: builtinRepeat},
+	"substr":      {Fn: builtinSubstr},
+	"pad_left":    {Fn: padFn("pad_left", true)},
+	"pad_right":   {Fn: padFn("pad_right", false)},
+	"chars":       {Fn: builtinChars},
+	"format":      {Fn: builtinFormat},
+	"puts":        {Fn: builtinPuts},
+	"print":       {Fn: builtinPrint},
+	"printf":      {Fn: builtinPrintf},
+	"eprint":      {Fn: builtinEprint},
+	"read_line":   {Fn: builtinReadLine},
+	"read_all":    {Fn: builtinReadAll},
+	"json_encode": {Fn: builtinJSONEncode},
+	"json_decode": {Fn: builtinJSONDecode},
+
+	"regex":          {Fn: builtinRegex},
+	"regex_match":    {Fn: builtinRegexMatch},
+	"regex_find_all": {Fn: builtinRegexFindAll},
+	"regex_replace":  {Fn: builtinRegexReplace},
+	"regex_split":    {Fn: builtinRegexSplit},
+
+	"fs.read_file":  {Fn: builtinReadFile},
+	"fs.write_file": {Fn: builtinWriteFile},
+	"fs.list_dir":   {Fn: builtinListDir},
+	"fs.exists":     {Fn: builtinExists},
+	"fs.mkdir":      {Fn: builtinMkdir},
+	"fs.remove":     {Fn: builtinRemove},
 }
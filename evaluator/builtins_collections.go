@@ -0,0 +1,425 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrWrongNumberOfArgsRange = "wrong number of arguments. got=%d, want=%d or %d"
+	ErrInvalidArgType         = "invalid argument: %s argument for %s must be %s. got=%s (%s)"
+	ErrReduceEmpty            = "invalid argument: reduce of empty array with no initial value"
+	ErrInvalidComparatorRes   = "invalid result: comparator for sort must return an integer or a boolean. got=%s (%s)"
+	ErrNotComparable          = "invalid argument: %s (%s) and %s (%s) can't be compared"
+)
+
+var ordinals = []string{"first", "second", "third", "fourth"}
+
+func checkArgCount(args []object.Object, min, max int) *object.Exception {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgs, len(args), min)
+	}
+	return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsRange, len(args), min, max)
+}
+
+func arrayArg(name string, args []object.Object, i int) (*object.Array, *object.Exception) {
+	arr, isArr := args[i].(*object.Array)
+	if !isArr {
+		return nil, newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "an array", args[i].Inspect(), args[i].Type())
+	}
+	return arr, nil
+}
+
+func functionArg(name string, args []object.Object, i int) *object.Exception {
+	if t := args[i].Type(); t != object.FUNCTION && t != object.BUILTIN {
+		return newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "a function", args[i].Inspect(), t)
+	}
+	return nil
+}
+
+// collectionArgs checks the (array, function) arguments shared by most of the
+// collection builtins.
+func collectionArgs(name string, args []object.Object) (*object.Array, *object.Exception) {
+	if exc := checkArgCount(args, 2, 2); exc != nil {
+		return nil, exc
+	}
+	arr, exc := arrayArg(name, args, 0)
+	if exc != nil {
+		return nil, exc
+	}
+	if exc := functionArg(name, args, 1); exc != nil {
+		return nil, exc
+	}
+	return arr, nil
+}
+
+func builtinMap(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("map", args)
+	if exc != nil {
+		return exc
+	}
+
+	res := make([]object.Object, len(arr.Elems))
+	for i, elem := range arr.Elems {
+		val := c.Call(args[1], elem)
+		if isUnwinding(val) {
+			return val
+		}
+		res[i] = val
+	}
+
+	return &object.Array{Elems: res}
+}
+
+func builtinFilter(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("filter", args)
+	if exc != nil {
+		return exc
+	}
+
+	res := []object.Object{}
+	for _, elem := range arr.Elems {
+		keep := c.Call(args[1], elem)
+		if isUnwinding(keep) {
+			return keep
+		}
+		if isTruthy(keep) {
+			res = append(res, elem)
+		}
+	}
+
+	return &object.Array{Elems: res}
+}
+
+func builtinReduce(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 2, 3); exc != nil {
+		return exc
+	}
+	arr, exc := arrayArg("reduce", args, 0)
+	if exc != nil {
+		return exc
+	}
+	if exc := functionArg("reduce", args, 1); exc != nil {
+		return exc
+	}
+
+	elems := arr.Elems
+	var acc object.Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else if len(elems) > 0 {
+		acc, elems = elems[0], elems[1:]
+	} else {
+		return newError(object.ARGUMENT_ERROR, ErrReduceEmpty)
+	}
+
+	for _, elem := range elems {
+		acc = c.Call(args[1], acc, elem)
+		if isUnwinding(acc) {
+			return acc
+		}
+	}
+
+	return acc
+}
+
+func builtinEach(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("each", args)
+	if exc != nil {
+		return exc
+	}
+
+	for _, elem := range arr.Elems {
+		if res := c.Call(args[1], elem); isUnwinding(res) {
+			return res
+		}
+	}
+
+	return NULL
+}
+
+func builtinFind(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("find", args)
+	if exc != nil {
+		return exc
+	}
+
+	for _, elem := range arr.Elems {
+		found := c.Call(args[1], elem)
+		if isUnwinding(found) {
+			return found
+		}
+		if isTruthy(found) {
+			return elem
+		}
+	}
+
+	return NULL
+}
+
+func builtinAny(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("any", args)
+	if exc != nil {
+		return exc
+	}
+
+	for _, elem := range arr.Elems {
+		res := c.Call(args[1], elem)
+		if isUnwinding(res) {
+			return res
+		}
+		if isTruthy(res) {
+			return TRUE
+		}
+	}
+
+	return FALSE
+}
+
+func builtinAll(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("all", args)
+	if exc != nil {
+		return exc
+	}
+
+	for _, elem := range arr.Elems {
+		res := c.Call(args[1], elem)
+		if isUnwinding(res) {
+			return res
+		}
+		if !isTruthy(res) {
+			return FALSE
+		}
+	}
+
+	return TRUE
+}
+
+// builtinSort returns a sorted copy of an array. The optional comparator
+// returns a negative, zero or positive integer like a - b, or a boolean
+// telling whether its first argument goes first. The sort is stable.
+func builtinSort(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 2); exc != nil {
+		return exc
+	}
+	arr, exc := arrayArg("sort", args, 0)
+	if exc != nil {
+		return exc
+	}
+	if len(args) == 2 {
+		if exc := functionArg("sort", args, 1); exc != nil {
+			return exc
+		}
+	}
+
+	sorted := make([]object.Object, len(arr.Elems))
+	copy(sorted, arr.Elems)
+
+	// The first failure stops further comparisons and is reported once the
+	// sort returns
+	var failure object.Object
+	less := func(a, b object.Object) bool {
+		if failure != nil {
+			return false
+		}
+		if len(args) == 1 {
+			cmp, exc := compareObjects(a, b)
+			if exc != nil {
+				failure = exc
+			}
+			return cmp < 0
+		}
+
+		res := c.Call(args[1], a, b)
+		switch res := res.(type) {
+		case *object.Integer:
+			return res.Value < 0
+		case *object.Boolean:
+			return res.Value
+		default:
+			if isUnwinding(res) {
+				failure = res
+			} else {
+				failure = newError(object.TYPE_ERROR, ErrInvalidComparatorRes, res.Inspect(), res.Type())
+			}
+			return false
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	if failure != nil {
+		return failure
+	}
+
+	return &object.Array{Elems: sorted}
+}
+
+// builtinZip pairs up the elements of its array arguments, stopping at the
+// end of the shortest one.
+func builtinZip(c object.CallContext, args ...object.Object) object.Object {
+	arrs := make([]*object.Array, len(args))
+	shortest := -1
+	for i := range args {
+		arr, isArr := args[i].(*object.Array)
+		if !isArr {
+			return newError(object.TYPE_ERROR, ErrInvalidArgType, "every", "zip", "an array", args[i].Inspect(), args[i].Type())
+		}
+		arrs[i] = arr
+		if shortest == -1 || len(arr.Elems) < shortest {
+			shortest = len(arr.Elems)
+		}
+	}
+
+	res := make([]object.Object, max(shortest, 0))
+	for i := range res {
+		tuple := make([]object.Object, len(arrs))
+		for j, arr := range arrs {
+			tuple[j] = arr.Elems[i]
+		}
+		res[i] = &object.Array{Elems: tuple}
+	}
+
+	return &object.Array{Elems: res}
+}
+
+// builtinFlatMap maps like map and concatenates the resulting arrays. Results
+// that are not arrays are kept as they are.
+func builtinFlatMap(c object.CallContext, args ...object.Object) object.Object {
+	arr, exc := collectionArgs("flat_map", args)
+	if exc != nil {
+		return exc
+	}
+
+	res := []object.Object{}
+	for _, elem := range arr.Elems {
+		val := c.Call(args[1], elem)
+		if isUnwinding(val) {
+			return val
+		}
+		if inner, isArr := val.(*object.Array); isArr {
+			res = append(res, inner.Elems...)
+		} else {
+			res = append(res, val)
+		}
+	}
+
+	return &object.Array{Elems: res}
+}
+
+func hashArg(name string, args []object.Object, i int) (*object.Hash, *object.Exception) {
+	hash, isHash := args[i].(*object.Hash)
+	if !isHash {
+		return nil, newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "a hash", args[i].Inspect(), args[i].Type())
+	}
+	return hash, nil
+}
+
+// builtinKeys returns the keys of a hash in insertion order.
+func builtinKeys(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 1); exc != nil {
+		return exc
+	}
+	hash, exc := hashArg("keys", args, 0)
+	if exc != nil {
+		return exc
+	}
+
+	keys := make([]object.Object, len(hash.Keys))
+	for i, key := range hash.Keys {
+		keys[i] = hash.Pairs[key].Key
+	}
+	return &object.Array{Elems: keys}
+}
+
+// builtinValues returns the values of a hash in the order of its keys.
+func builtinValues(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 1); exc != nil {
+		return exc
+	}
+	hash, exc := hashArg("values", args, 0)
+	if exc != nil {
+		return exc
+	}
+
+	values := make([]object.Object, len(hash.Keys))
+	for i, key := range hash.Keys {
+		values[i] = hash.Pairs[key].Value
+	}
+	return &object.Array{Elems: values}
+}
+
+func builtinHas(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 2, 2); exc != nil {
+		return exc
+	}
+	hash, exc := hashArg("has", args, 0)
+	if exc != nil {
+		return exc
+	}
+
+	key, isHashable := args[1].(object.Hashable)
+	if !isHashable {
+		return newError(object.INDEX_ERROR, ErrUnhashableKey, args[1].Inspect(), args[1].Type())
+	}
+	_, exists := hash.Get(key)
+	return nativeBoolToObjBool(exists)
+}
+
+// builtinFreeze makes arrays, hashes and structs immutable, along with every
+// value inside them, and returns its argument.
+func builtinFreeze(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 1); exc != nil {
+		return exc
+	}
+	freeze(args[0])
+	return args[0]
+}
+
+func freeze(obj object.Object) {
+	// Already frozen values are skipped, which also stops at cycles
+	if isFrozen(obj) {
+		return
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		obj.Frozen = true
+		for _, elem := range obj.Elems {
+			freeze(elem)
+		}
+	case *object.Hash:
+		obj.Frozen = true
+		for _, pair := range obj.Pairs {
+			freeze(pair.Value)
+		}
+	case *object.Struct:
+		obj.Frozen = true
+		for _, val := range obj.Values {
+			freeze(val)
+		}
+	}
+}
+
+func isFrozen(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return obj.Frozen
+	case *object.Hash:
+		return obj.Frozen
+	case *object.Struct:
+		return obj.Frozen
+	default:
+		return false
+	}
+}
+
+func builtinIsFrozen(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 1); exc != nil {
+		return exc
+	}
+	return nativeBoolToObjBool(isFrozen(args[0]))
+}
@@ -0,0 +1,120 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/vfs"
+)
+
+const ErrNoFilesystem = "filesystem access is not enabled"
+
+// fsCall checks that the host granted filesystem access and that the
+// arguments are n strings, the first being a path.
+func fsCall(c object.CallContext, name string, args []object.Object, n int) (vfs.FS, []string, *object.Exception) {
+	strs, exc := stringArgs(name, args, n)
+	if exc != nil {
+		return nil, nil, exc
+	}
+	if c.FS() == nil {
+		return nil, nil, newError(object.PERMISSION_ERROR, ErrNoFilesystem)
+	}
+	return c.FS(), strs, nil
+}
+
+// fsError turns a filesystem error into an error object. Leaving the sandbox
+// is a PermissionError, any other failure a HostError.
+func fsError(err error) *object.Exception {
+	if errors.Is(err, vfs.ErrOutsideRoot) || errors.Is(err, fs.ErrPermission) {
+		return newError(object.PERMISSION_ERROR, "%s", err)
+	}
+	return newError(object.HOST_ERROR, "%s", err)
+}
+
+func builtinReadFile(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.read_file", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	data, err := fsys.ReadFile(strs[0])
+	if err != nil {
+		return fsError(err)
+	}
+	return &object.String{Value: string(data)}
+}
+
+func builtinWriteFile(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.write_file", args, 2)
+	if exc != nil {
+		return exc
+	}
+
+	if err := fsys.WriteFile(strs[0], []byte(strs[1])); err != nil {
+		return fsError(err)
+	}
+	return NULL
+}
+
+// builtinListDir returns the sorted names of a directory's entries.
+func builtinListDir(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.list_dir", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	entries, err := fsys.ReadDir(strs[0])
+	if err != nil {
+		return fsError(err)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return stringsToArray(names)
+}
+
+func builtinExists(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.exists", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	_, err := fsys.Stat(strs[0])
+	switch {
+	case err == nil:
+		return TRUE
+	case errors.Is(err, fs.ErrNotExist):
+		return FALSE
+	default:
+		return fsError(err)
+	}
+}
+
+// builtinMkdir creates a directory and any missing parents.
+func builtinMkdir(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.mkdir", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	if err := fsys.MkdirAll(strs[0]); err != nil {
+		return fsError(err)
+	}
+	return NULL
+}
+
+// builtinRemove removes a file or an empty directory.
+func builtinRemove(c object.CallContext, args ...object.Object) object.Object {
+	fsys, strs, exc := fsCall(c, "fs.remove", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	if err := fsys.Remove(strs[0]); err != nil {
+		return fsError(err)
+	}
+	return NULL
+}
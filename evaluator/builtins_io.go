@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrWriteFailed = "write failed: %s"
+	ErrReadFailed  = "read failed: %s"
+)
+
+// joinInspected joins the Inspect output of objs with spaces.
+func joinInspected(objs []object.Object) string {
+	strs := make([]string, len(objs))
+	for i, obj := range objs {
+		strs[i] = obj.Inspect()
+	}
+	return strings.Join(strs, " ")
+}
+
+func write(w io.Writer, s string) object.Object {
+	if _, err := io.WriteString(w, s); err != nil {
+		return newError(object.HOST_ERROR, ErrWriteFailed, err)
+	}
+	return NULL
+}
+
+// builtinPuts writes each argument on its own line.
+func builtinPuts(c object.CallContext, args ...object.Object) object.Object {
+	var out strings.Builder
+	for _, arg := range args {
+		out.WriteString(arg.Inspect())
+		out.WriteString("\n")
+	}
+	return write(c.Out(), out.String())
+}
+
+// builtinPrint writes its arguments separated by spaces, without a trailing
+// newline.
+func builtinPrint(c object.CallContext, args ...object.Object) object.Object {
+	return write(c.Out(), joinInspected(args))
+}
+
+// builtinEprint is print for the error output.
+func builtinEprint(c object.CallContext, args ...object.Object) object.Object {
+	return write(c.ErrOut(), joinInspected(args))
+}
+
+// builtinPrintf writes its arguments formatted like format does.
+func builtinPrintf(c object.CallContext, args ...object.Object) object.Object {
+	formatted := builtinFormat(c, args...)
+	str, isStr := formatted.(*object.String)
+	if !isStr {
+		return formatted
+	}
+	return write(c.Out(), str.Value)
+}
+
+// builtinReadLine reads a line of input without its line ending. It returns
+// null once the input is exhausted.
+func builtinReadLine(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 0, 0); exc != nil {
+		return exc
+	}
+
+	line, err := c.In().ReadString('\n')
+	switch {
+	case errors.Is(err, io.EOF) && line == "":
+		return NULL
+	case err != nil && !errors.Is(err, io.EOF):
+		return newError(object.HOST_ERROR, ErrReadFailed, err)
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &object.String{Value: line}
+}
+
+// builtinReadAll reads the rest of the input.
+func builtinReadAll(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 0, 0); exc != nil {
+		return exc
+	}
+
+	all, err := io.ReadAll(c.In())
+	if err != nil {
+		return newError(object.HOST_ERROR, ErrReadFailed, err)
+	}
+	return &object.String{Value: string(all)}
+}
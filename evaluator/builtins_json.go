@@ -0,0 +1,258 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrJSONUnsupported = "invalid argument: %s can't be encoded as JSON"
+	ErrJSONCycle       = "invalid argument: cyclic structure can't be encoded as JSON"
+	ErrJSONSyntax      = "invalid JSON at line %d, column %d: %s"
+)
+
+// builtinJSONEncode encodes a value as JSON. Hash keys are written in
+// insertion order, with integer and boolean keys turned into strings. An
+// indent, either a number of spaces or a string, pretty prints the output.
+func builtinJSONEncode(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 2); exc != nil {
+		return exc
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *object.Integer:
+			indent = strings.Repeat(" ", int(max(arg.Value, 0)))
+		case *object.String:
+			indent = arg.Value
+		default:
+			return newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[1], "json_encode", "an integer or a string", arg.Inspect(), arg.Type())
+		}
+	}
+
+	enc := &jsonEncoder{seen: map[object.Object]bool{}}
+	if exc := enc.encode(args[0]); exc != nil {
+		return exc
+	}
+	if indent == "" {
+		return &object.String{Value: enc.buf.String()}
+	}
+
+	var out bytes.Buffer
+	json.Indent(&out, enc.buf.Bytes(), "", indent)
+	return &object.String{Value: out.String()}
+}
+
+type jsonEncoder struct {
+	buf bytes.Buffer
+	// seen holds the arrays and hashes being encoded, to catch cycles
+	seen map[object.Object]bool
+}
+
+func (enc *jsonEncoder) encode(obj object.Object) *object.Exception {
+	switch obj := obj.(type) {
+	case *object.Null:
+		enc.buf.WriteString("null")
+	case *object.Boolean:
+		enc.buf.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Integer:
+		enc.buf.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.String:
+		enc.writeString(obj.Value)
+	case *object.Array:
+		if enc.seen[obj] {
+			return newError(object.ARGUMENT_ERROR, ErrJSONCycle)
+		}
+		enc.seen[obj] = true
+		defer delete(enc.seen, obj)
+
+		enc.buf.WriteByte('[')
+		for i, elem := range obj.Elems {
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			if exc := enc.encode(elem); exc != nil {
+				return exc
+			}
+		}
+		enc.buf.WriteByte(']')
+	case *object.Hash:
+		if enc.seen[obj] {
+			return newError(object.ARGUMENT_ERROR, ErrJSONCycle)
+		}
+		enc.seen[obj] = true
+		defer delete(enc.seen, obj)
+
+		enc.buf.WriteByte('{')
+		for i, key := range obj.Keys {
+			pair := obj.Pairs[key]
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			if str, isStr := pair.Key.(*object.String); isStr {
+				enc.writeString(str.Value)
+			} else {
+				enc.writeString(pair.Key.Inspect())
+			}
+			enc.buf.WriteByte(':')
+			if exc := enc.encode(pair.Value); exc != nil {
+				return exc
+			}
+		}
+		enc.buf.WriteByte('}')
+	case *object.Struct:
+		if enc.seen[obj] {
+			return newError(object.ARGUMENT_ERROR, ErrJSONCycle)
+		}
+		enc.seen[obj] = true
+		defer delete(enc.seen, obj)
+
+		enc.buf.WriteByte('{')
+		for i, field := range obj.StructType.Fields {
+			if i > 0 {
+				enc.buf.WriteByte(',')
+			}
+			enc.writeString(field)
+			enc.buf.WriteByte(':')
+			if exc := enc.encode(obj.Values[i]); exc != nil {
+				return exc
+			}
+		}
+		enc.buf.WriteByte('}')
+	default:
+		return newError(object.TYPE_ERROR, ErrJSONUnsupported, obj.Type())
+	}
+	return nil
+}
+
+func (enc *jsonEncoder) writeString(s string) {
+	var b bytes.Buffer
+	strEnc := json.NewEncoder(&b)
+	strEnc.SetEscapeHTML(false)
+	// Encoding a string can't fail
+	strEnc.Encode(s)
+	enc.buf.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+}
+
+// builtinJSONDecode decodes a JSON document. Objects become hashes keeping
+// the order of their keys. Numbers must be integers.
+func builtinJSONDecode(c object.CallContext, args ...object.Object) object.Object {
+	strs, exc := stringArgs("json_decode", args, 1)
+	if exc != nil {
+		return exc
+	}
+	src := strs[0]
+
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+
+	val, err := decodeJSON(dec)
+	if err == nil {
+		end := dec.InputOffset()
+		if _, err = dec.Token(); errors.Is(err, io.EOF) {
+			return val
+		}
+		if err == nil {
+			trailing := len(src[end:]) - len(strings.TrimLeft(src[end:], " \t\r\n"))
+			err = &jsonError{msg: "unexpected data after top-level value", offset: end + int64(trailing)}
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	var jsonErr *jsonError
+	offset := int64(len(src))
+	msg := err.Error()
+	switch {
+	case errors.As(err, &syntaxErr):
+		// The offset is just past the offending character
+		offset = syntaxErr.Offset - 1
+	case errors.As(err, &jsonErr):
+		offset = jsonErr.offset
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		msg = "unexpected end of JSON input"
+	}
+	line, col := position(src, offset)
+	return newError(object.ARGUMENT_ERROR, ErrJSONSyntax, line, col, msg)
+}
+
+// jsonError is a decoding error the json package doesn't detect itself.
+type jsonError struct {
+	msg    string
+	offset int64
+}
+
+func (e *jsonError) Error() string { return e.msg }
+
+func decodeJSON(dec *json.Decoder) (object.Object, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return NULL, nil
+	case bool:
+		return nativeBoolToObjBool(tok), nil
+	case string:
+		return &object.String{Value: tok}, nil
+	case json.Number:
+		n, err := tok.Int64()
+		if err != nil {
+			offset := dec.InputOffset() - int64(len(tok))
+			return nil, &jsonError{msg: "number " + tok.String() + " is not an integer", offset: offset}
+		}
+		return &object.Integer{Value: n}, nil
+	case json.Delim:
+		if tok == '[' {
+			elems := []object.Object{}
+			for dec.More() {
+				elem, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				elems = append(elems, elem)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elems: elems}, nil
+		}
+
+		hash := object.NewHash()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(&object.String{Value: key.(string)}, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+	default:
+		return nil, &jsonError{msg: "unexpected token", offset: dec.InputOffset()}
+	}
+}
+
+// position turns a byte offset into src into a 1-based line and column, the
+// column counting runes.
+func position(src string, offset int64) (line, col int) {
+	before := src[:min(offset, int64(len(src)))]
+	line = strings.Count(before, "\n") + 1
+	col = utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return line, col
+}
@@ -0,0 +1,173 @@
+package evaluator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrInvalidRegex       = "invalid argument: %s is not a valid regex: %s"
+	ErrInvalidReplacement = "invalid result: replacement function for regex_replace must return a string. got=%s (%s)"
+)
+
+// regexArg accepts a regex or a string pattern, which is compiled on every
+// call. Build a regex with a literal or with regex() to compile it once.
+func regexArg(name string, args []object.Object, i int) (*regexp.Regexp, *object.Exception) {
+	switch arg := args[i].(type) {
+	case *object.Regex:
+		return arg.Regexp, nil
+	case *object.String:
+		return compileRegex(arg.Value)
+	default:
+		return nil, newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "a regex or a string", arg.Inspect(), arg.Type())
+	}
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, *object.Exception) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, newError(object.ARGUMENT_ERROR, ErrInvalidRegex, pattern, err)
+	}
+	return re, nil
+}
+
+// regexArgs checks the (regex, string) arguments the regex builtins start
+// with, allowing up to extra more.
+func regexArgs(name string, args []object.Object, extra int) (*regexp.Regexp, string, *object.Exception) {
+	if exc := checkArgCount(args, 2, 2+extra); exc != nil {
+		return nil, "", exc
+	}
+	re, exc := regexArg(name, args, 0)
+	if exc != nil {
+		return nil, "", exc
+	}
+	s, exc := stringArg(name, args, 1)
+	if exc != nil {
+		return nil, "", exc
+	}
+	return re, s, nil
+}
+
+// limitArg reads an optional maximum count at index i. Without one there is
+// no limit.
+func limitArg(name string, args []object.Object, i int) (int, *object.Exception) {
+	if len(args) <= i {
+		return -1, nil
+	}
+	n, exc := integerArg(name, args, i)
+	return int(n), exc
+}
+
+// matchObject describes one match: the matched text for a regex without
+// groups, otherwise an array of the text and each group, null for groups that
+// didn't take part.
+func matchObject(re *regexp.Regexp, s string, loc []int) object.Object {
+	if re.NumSubexp() == 0 {
+		return &object.String{Value: s[loc[0]:loc[1]]}
+	}
+
+	groups := make([]object.Object, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] < 0 {
+			groups[i] = NULL
+		} else {
+			groups[i] = &object.String{Value: s[loc[2*i]:loc[2*i+1]]}
+		}
+	}
+	return &object.Array{Elems: groups}
+}
+
+func builtinRegex(c object.CallContext, args ...object.Object) object.Object {
+	strs, exc := stringArgs("regex", args, 1)
+	if exc != nil {
+		return exc
+	}
+	re, exc := compileRegex(strs[0])
+	if exc != nil {
+		return exc
+	}
+	return &object.Regex{Regexp: re}
+}
+
+func builtinRegexMatch(c object.CallContext, args ...object.Object) object.Object {
+	re, s, exc := regexArgs("regex_match", args, 0)
+	if exc != nil {
+		return exc
+	}
+	return nativeBoolToObjBool(re.MatchString(s))
+}
+
+// builtinRegexFindAll returns every match, up to an optional limit, as
+// described by matchObject.
+func builtinRegexFindAll(c object.CallContext, args ...object.Object) object.Object {
+	re, s, exc := regexArgs("regex_find_all", args, 1)
+	if exc != nil {
+		return exc
+	}
+	n, exc := limitArg("regex_find_all", args, 2)
+	if exc != nil {
+		return exc
+	}
+
+	locs := re.FindAllStringSubmatchIndex(s, n)
+	matches := make([]object.Object, len(locs))
+	for i, loc := range locs {
+		matches[i] = matchObject(re, s, loc)
+	}
+	return &object.Array{Elems: matches}
+}
+
+// builtinRegexReplace replaces every match. The replacement is either a
+// string, where $1 or ${name} refer to groups, or a function that receives
+// the match as described by matchObject and returns the replacement.
+func builtinRegexReplace(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 3, 3); exc != nil {
+		return exc
+	}
+	re, s, exc := regexArgs("regex_replace", args[:2], 0)
+	if exc != nil {
+		return exc
+	}
+
+	if repl, isStr := args[2].(*object.String); isStr {
+		return &object.String{Value: re.ReplaceAllString(s, repl.Value)}
+	}
+	if t := args[2].Type(); t != object.FUNCTION && t != object.BUILTIN {
+		return newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[2], "regex_replace", "a string or a function", args[2].Inspect(), args[2].Type())
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(s, -1) {
+		res := c.Call(args[2], matchObject(re, s, loc))
+		if isUnwinding(res) {
+			return res
+		}
+		str, isStr := res.(*object.String)
+		if !isStr {
+			return newError(object.TYPE_ERROR, ErrInvalidReplacement, res.Inspect(), res.Type())
+		}
+		out.WriteString(s[last:loc[0]])
+		out.WriteString(str.Value)
+		last = loc[1]
+	}
+	out.WriteString(s[last:])
+
+	return &object.String{Value: out.String()}
+}
+
+// builtinRegexSplit splits a string around the matches, into at most an
+// optional number of parts.
+func builtinRegexSplit(c object.CallContext, args ...object.Object) object.Object {
+	re, s, exc := regexArgs("regex_split", args, 1)
+	if exc != nil {
+		return exc
+	}
+	n, exc := limitArg("regex_split", args, 2)
+	if exc != nil {
+		return exc
+	}
+	return stringsToArray(re.Split(s, n))
+}
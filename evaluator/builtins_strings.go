@@ -0,0 +1,398 @@
+package evaluator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrNegativeCount = "invalid argument: count for %s must not be negative. got=%d"
+	ErrEmptyPad      = "invalid argument: padding for %s must not be empty"
+	ErrFormatVerb    = "invalid argument: verb %s for format can't print %s (%s)"
+	ErrUnknownVerb   = "invalid argument: unknown verb %s for format"
+	ErrMissingVerb   = "invalid argument: format ends in the middle of verb %s"
+	ErrFormatMissing = "invalid argument: verb %s for format has no argument"
+	ErrFormatExtra   = "invalid argument: format uses %d of its %d arguments"
+)
+
+func stringArg(name string, args []object.Object, i int) (string, *object.Exception) {
+	s, isStr := args[i].(*object.String)
+	if !isStr {
+		return "", newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "a string", args[i].Inspect(), args[i].Type())
+	}
+	return s.Value, nil
+}
+
+func integerArg(name string, args []object.Object, i int) (int64, *object.Exception) {
+	n, isInt := args[i].(*object.Integer)
+	if !isInt {
+		return 0, newError(object.TYPE_ERROR, ErrInvalidArgType, ordinals[i], name, "an integer", args[i].Inspect(), args[i].Type())
+	}
+	return n.Value, nil
+}
+
+// stringArgs checks that all arguments are strings and that there are
+// exactly n of them.
+func stringArgs(name string, args []object.Object, n int) ([]string, *object.Exception) {
+	if exc := checkArgCount(args, n, n); exc != nil {
+		return nil, exc
+	}
+	strs := make([]string, n)
+	for i := range args {
+		s, exc := stringArg(name, args, i)
+		if exc != nil {
+			return nil, exc
+		}
+		strs[i] = s
+	}
+	return strs, nil
+}
+
+// stringFn adapts a func(string) string into a one argument builtin.
+func stringFn(name string, fn func(string) string) object.BuiltinFn {
+	return func(c object.CallContext, args ...object.Object) object.Object {
+		strs, exc := stringArgs(name, args, 1)
+		if exc != nil {
+			return exc
+		}
+		return &object.String{Value: fn(strs[0])}
+	}
+}
+
+// stringPredicate adapts a func(s, sub string) bool into a two argument
+// builtin.
+func stringPredicate(name string, fn func(string, string) bool) object.BuiltinFn {
+	return func(c object.CallContext, args ...object.Object) object.Object {
+		strs, exc := stringArgs(name, args, 2)
+		if exc != nil {
+			return exc
+		}
+		return nativeBoolToObjBool(fn(strs[0], strs[1]))
+	}
+}
+
+func stringsToArray(strs []string) *object.Array {
+	elems := make([]object.Object, len(strs))
+	for i, s := range strs {
+		elems[i] = &object.String{Value: s}
+	}
+	return &object.Array{Elems: elems}
+}
+
+// chars splits s into strings holding one rune each.
+func chars(s string) []string {
+	strs := make([]string, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		strs = append(strs, string(r))
+	}
+	return strs
+}
+
+func builtinSplit(c object.CallContext, args ...object.Object) object.Object {
+	strs, exc := stringArgs("split", args, 2)
+	if exc != nil {
+		return exc
+	}
+	if strs[1] == "" {
+		return stringsToArray(chars(strs[0]))
+	}
+	return stringsToArray(strings.Split(strs[0], strs[1]))
+}
+
+func builtinJoin(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 1, 2); exc != nil {
+		return exc
+	}
+	arr, exc := arrayArg("join", args, 0)
+	if exc != nil {
+		return exc
+	}
+	sep := ""
+	if len(args) == 2 {
+		if sep, exc = stringArg("join", args, 1); exc != nil {
+			return exc
+		}
+	}
+
+	strs := make([]string, len(arr.Elems))
+	for i, elem := range arr.Elems {
+		strs[i] = elem.Inspect()
+	}
+
+	return &object.String{Value: strings.Join(strs, sep)}
+}
+
+func builtinIndexOf(c object.CallContext, args ...object.Object) object.Object {
+	strs, exc := stringArgs("index_of", args, 2)
+	if exc != nil {
+		return exc
+	}
+
+	i := strings.Index(strs[0], strs[1])
+	if i < 0 {
+		return &object.Integer{Value: -1}
+	}
+	return &object.Integer{Value: int64(utf8.RuneCountInString(strs[0][:i]))}
+}
+
+func builtinReplace(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 3, 4); exc != nil {
+		return exc
+	}
+	strs, exc := stringArgs("replace", args[:3], 3)
+	if exc != nil {
+		return exc
+	}
+	n := int64(-1)
+	if len(args) == 4 {
+		if n, exc = integerArg("replace", args, 3); exc != nil {
+			return exc
+		}
+	}
+
+	return &object.String{Value: strings.Replace(strs[0], strs[1], strs[2], int(n))}
+}
+
+func builtinRepeat(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 2, 2); exc != nil {
+		return exc
+	}
+	s, exc := stringArg("repeat", args, 0)
+	if exc != nil {
+		return exc
+	}
+	n, exc := integerArg("repeat", args, 1)
+	if exc != nil {
+		return exc
+	}
+	if n < 0 {
+		return newError(object.ARGUMENT_ERROR, ErrNegativeCount, "repeat", n)
+	}
+	if exc := reserve(c, "repeat", repeatSize(s, n)); exc != nil {
+		return exc
+	}
+
+	return &object.String{Value: strings.Repeat(s, int(n))}
+}
+
+// repeatSize returns the bytes in s repeated n times, or -1 if that overflows.
+func repeatSize(s string, n int64) int64 {
+	if n > 0 && int64(len(s)) > math.MaxInt64/n {
+		return -1
+	}
+	return int64(len(s)) * n
+}
+
+// builtinSubstr returns length runes of a string starting at start. A
+// negative start counts from the end and the range is clamped to the string.
+// Without a length it runs to the end of the string.
+func builtinSubstr(c object.CallContext, args ...object.Object) object.Object {
+	if exc := checkArgCount(args, 2, 3); exc != nil {
+		return exc
+	}
+	s, exc := stringArg("substr", args, 0)
+	if exc != nil {
+		return exc
+	}
+	start, exc := integerArg("substr", args, 1)
+	if exc != nil {
+		return exc
+	}
+
+	runes := []rune(s)
+	size := int64(len(runes))
+	if start < 0 {
+		start += size
+	}
+	start = clamp(start, 0, size)
+
+	end := size
+	if len(args) == 3 {
+		length, exc := integerArg("substr", args, 2)
+		if exc != nil {
+			return exc
+		}
+		// Clamped before adding so a huge length can't overflow
+		end = start + clamp(length, 0, size-start)
+	}
+
+	return &object.String{Value: string(runes[start:end])}
+}
+
+func clamp(n, lo, hi int64) int64 {
+	return min(max(n, lo), hi)
+}
+
+// padFn builds pad_left and pad_right, which pad a string to a width in runes
+// with spaces or with the given padding.
+func padFn(name string, left bool) object.BuiltinFn {
+	return func(c object.CallContext, args ...object.Object) object.Object {
+		if exc := checkArgCount(args, 2, 3); exc != nil {
+			return exc
+		}
+		s, exc := stringArg(name, args, 0)
+		if exc != nil {
+			return exc
+		}
+		width, exc := integerArg(name, args, 1)
+		if exc != nil {
+			return exc
+		}
+		pad := " "
+		if len(args) == 3 {
+			if pad, exc = stringArg(name, args, 2); exc != nil {
+				return exc
+			}
+			if pad == "" {
+				return newError(object.ARGUMENT_ERROR, ErrEmptyPad, name)
+			}
+		}
+
+		missing := width - int64(utf8.RuneCountInString(s))
+		if missing <= 0 {
+			return &object.String{Value: s}
+		}
+		padRunes := int64(utf8.RuneCountInString(pad))
+		if exc := reserve(c, name, repeatSize(pad, missing/padRunes+1)); exc != nil {
+			return exc
+		}
+		padding := strings.Repeat(pad, int(missing/padRunes)) + string([]rune(pad)[:missing%padRunes])
+
+		if left {
+			return &object.String{Value: padding + s}
+		}
+		return &object.String{Value: s + padding}
+	}
+}
+
+func builtinChars(c object.CallContext, args ...object.Object) object.Object {
+	strs, exc := stringArgs("chars", args, 1)
+	if exc != nil {
+		return exc
+	}
+	return stringsToArray(chars(strs[0]))
+}
+
+// builtinFormat formats its arguments with Go's printf verbs. Integers, strings
+// and booleans are passed as themselves, other values as their Inspect
+// output.
+func builtinFormat(c object.CallContext, args ...object.Object) object.Object {
+	if len(args) < 1 {
+		return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsVariadic, len(args), 1)
+	}
+	format, exc := stringArg("format", args, 0)
+	if exc != nil {
+		return exc
+	}
+	if exc := checkFormat(format, args[1:]); exc != nil {
+		return exc
+	}
+
+	return &object.String{Value: fmt.Sprintf(format, formatArgs(args[1:])...)}
+}
+
+// formatVerbs lists the verbs format accepts for each type of argument. Other
+// values are printed as their Inspect output, so they take the string verbs.
+var formatVerbs = map[object.ObjectType]string{
+	object.INTEGER: "vdbocqxXU",
+	object.BOOLEAN: "vt",
+	object.STRING:  "vsqxX",
+}
+
+// checkFormat checks that every verb in format has an argument of a type it
+// can print and that no argument is left over, which fmt would otherwise
+// report inside the result. Arguments can be picked with %[n]d and widths
+// given with *, as in fmt.
+func checkFormat(format string, args []object.Object) *object.Exception {
+	argNum, reordered := 0, false
+
+	// next takes the argument for the verb format[start:end] and checks its
+	// type against verbs
+	next := func(start, end int, verbs string) *object.Exception {
+		verb := format[start:end]
+		if argNum >= len(args) {
+			return newError(object.ARGUMENT_ERROR, ErrFormatMissing, verb)
+		}
+		arg := args[argNum]
+		argNum++
+
+		accepted, known := formatVerbs[arg.Type()]
+		if !known {
+			accepted = formatVerbs[object.STRING]
+		}
+		if !strings.ContainsAny(verbs, accepted) {
+			return newError(object.ARGUMENT_ERROR, ErrFormatVerb, verb, arg.Inspect(), arg.Type())
+		}
+		return nil
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		start := i
+
+	flags:
+		for i++; i < len(format); i++ {
+			switch ch := format[i]; {
+			case strings.IndexByte("+-# 0.", ch) >= 0, '0' <= ch && ch <= '9':
+			case ch == '*':
+				if exc := next(start, i+1, "d"); exc != nil {
+					return exc
+				}
+			case ch == '[':
+				end := strings.IndexByte(format[i:], ']')
+				n, err := strconv.Atoi(format[i+1 : i+max(end, 1)])
+				if end < 0 || err != nil || n < 1 {
+					return newError(object.ARGUMENT_ERROR, ErrUnknownVerb, format[start:i+1])
+				}
+				argNum, reordered = n-1, true
+				i += end
+			default:
+				break flags
+			}
+		}
+
+		if i >= len(format) {
+			return newError(object.ARGUMENT_ERROR, ErrMissingVerb, format[start:])
+		}
+		verb, size := utf8.DecodeRuneInString(format[i:])
+		i += size - 1
+		switch {
+		case verb == '%':
+			continue
+		case !strings.ContainsRune("vdbocqxXUst", verb):
+			return newError(object.ARGUMENT_ERROR, ErrUnknownVerb, format[start:i+1])
+		}
+		if exc := next(start, i+1, string(verb)); exc != nil {
+			return exc
+		}
+	}
+
+	if !reordered && argNum < len(args) {
+		return newError(object.ARGUMENT_ERROR, ErrFormatExtra, argNum, len(args))
+	}
+	return nil
+}
+
+func formatArgs(args []object.Object) []any {
+	vals := make([]any, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case *object.Integer:
+			vals[i] = arg.Value
+		case *object.String:
+			vals[i] = arg.Value
+		case *object.Boolean:
+			vals[i] = arg.Value
+		default:
+			vals[i] = arg.Inspect()
+		}
+	}
+	return vals
+}
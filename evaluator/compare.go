@@ -0,0 +1,166 @@
+package evaluator
+
+import (
+	"cmp"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+// objectsEqual reports whether two values are structurally equal. Arrays are
+// equal when their elements are, hashes when they hold equal values under the
+// same keys regardless of order. Functions, builtins and errors are only equal
+// to themselves.
+func objectsEqual(a, b object.Object) bool {
+	return (&comparison{}).equal(a, b)
+}
+
+// comparison compares values that may contain themselves. comparing holds the
+// pairs of containers being compared further up. Meeting one again means the
+// values repeat the same way, so they are taken to be equal.
+type comparison struct {
+	comparing map[[2]object.Object]bool
+}
+
+// enter marks a and b as being compared. It reports false if they already
+// are, otherwise the caller must call leave once it is done with them.
+func (c *comparison) enter(a, b object.Object) bool {
+	pair := [2]object.Object{a, b}
+	if c.comparing[pair] {
+		return false
+	}
+	if c.comparing == nil {
+		c.comparing = make(map[[2]object.Object]bool)
+	}
+	c.comparing[pair] = true
+	return true
+}
+
+func (c *comparison) leave(a, b object.Object) {
+	delete(c.comparing, [2]object.Object{a, b})
+}
+
+func (c *comparison) equal(a, b object.Object) bool {
+	if a == b {
+		return true
+	}
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Integer:
+		return a.Value == b.(*object.Integer).Value
+	case *object.String:
+		return a.Value == b.(*object.String).Value
+	case *object.Boolean:
+		return a.Value == b.(*object.Boolean).Value
+	case *object.Null:
+		return true
+	case *object.Regex:
+		return a.Regexp.String() == b.(*object.Regex).Regexp.String()
+	case *object.Array, *object.Struct, *object.Hash:
+		if !c.enter(a, b) {
+			return true
+		}
+		defer c.leave(a, b)
+		return c.equalContainers(a, b)
+	default:
+		return false
+	}
+}
+
+func (c *comparison) equalContainers(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Array:
+		other := b.(*object.Array)
+		if len(a.Elems) != len(other.Elems) {
+			return false
+		}
+		for i := range a.Elems {
+			if !c.equal(a.Elems[i], other.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Struct:
+		other := b.(*object.Struct)
+		if a.StructType != other.StructType {
+			return false
+		}
+		for i := range a.Values {
+			if !c.equal(a.Values[i], other.Values[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		other := b.(*object.Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, exists := other.Pairs[key]
+			if !exists || !c.equal(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// compareObjects orders two integers, two strings or two arrays, returning a
+// negative, zero or positive integer. Arrays are ordered lexicographically by
+// their elements.
+func compareObjects(a, b object.Object) (int, *object.Exception) {
+	return (&comparison{}).compare(a, b)
+}
+
+func (c *comparison) compare(a, b object.Object) (int, *object.Exception) {
+	switch {
+	case a.Type() == object.INTEGER && b.Type() == object.INTEGER:
+		return cmp.Compare(a.(*object.Integer).Value, b.(*object.Integer).Value), nil
+	case a.Type() == object.STRING && b.Type() == object.STRING:
+		return cmp.Compare(a.(*object.String).Value, b.(*object.String).Value), nil
+	case a.Type() == object.ARRAY && b.Type() == object.ARRAY:
+		if !c.enter(a, b) {
+			return 0, nil
+		}
+		defer c.leave(a, b)
+
+		x, y := a.(*object.Array).Elems, b.(*object.Array).Elems
+		for i := 0; i < min(len(x), len(y)); i++ {
+			order, exc := c.compare(x[i], y[i])
+			if exc != nil || order != 0 {
+				return order, exc
+			}
+		}
+		return cmp.Compare(len(x), len(y)), nil
+	default:
+		return 0, newError(object.TYPE_ERROR, ErrNotComparable, a.Inspect(), a.Type(), b.Inspect(), b.Type())
+	}
+}
+
+func isOrdering(op string) bool {
+	return op == "<" || op == "<=" || op == ">" || op == ">="
+}
+
+// evalOrderingExpression evaluates < <= > >= with compareObjects.
+func evalOrderingExpression(op string, left, right object.Object) object.Object {
+	c, exc := compareObjects(left, right)
+	if exc != nil {
+		return exc
+	}
+
+	switch op {
+	case "<":
+		return nativeBoolToObjBool(c < 0)
+	case "<=":
+		return nativeBoolToObjBool(c <= 0)
+	case ">":
+		return nativeBoolToObjBool(c > 0)
+	default:
+		return nativeBoolToObjBool(c >= 0)
+	}
+}
@@ -0,0 +1,172 @@
+package evaluator
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/vfs"
+)
+
+const (
+	ErrCanceled         = "evaluation canceled"
+	ErrTimeout          = "evaluation timed out"
+	ErrStepBudget       = "step budget exhausted: limit=%d"
+	ErrAllocBudget      = "allocation budget exhausted: limit=%d"
+	ErrResultTooLarge   = "invalid argument: result of %s would be too large"
+	ErrMaxDepthExceeded = "maximum call depth exceeded: limit=%d"
+	DefaultMaxCallDepth = 10000
+
+	// MaxStringSize caps the bytes in a string built by repeat or padding, so
+	// that a huge count fails instead of exhausting memory
+	MaxStringSize = 1 << 30
+)
+
+// Options configures a single evaluation and bounds the work it is allowed to
+// do. A zero value for MaxSteps or MaxAllocs disables that budget.
+type Options struct {
+	// MaxSteps caps the number of function calls and block entries.
+	MaxSteps int64
+	// MaxAllocs caps the number of allocation units the program may create.
+	// Every new array or string costs one unit plus one per element or byte.
+	MaxAllocs int64
+	// MaxDepth caps the nesting of function calls. Zero means
+	// DefaultMaxCallDepth.
+	MaxDepth int
+
+	// Builtins are the builtins visible to the program. Nil means the
+	// standard builtins.
+	Builtins *Registry
+	// Stdout receives the program's output. Nil means os.Stdout.
+	Stdout io.Writer
+	// Stderr receives the program's diagnostics. Nil means os.Stderr.
+	Stderr io.Writer
+	// Stdin is read by read_line and read_all. Nil means os.Stdin. Pass a
+	// *bufio.Reader to share buffered input between evaluations.
+	Stdin io.Reader
+	// FS is the filesystem the fs builtins work on. Nil denies filesystem
+	// access.
+	FS vfs.FS
+
+	// File is the path of the script being evaluated, which its imports are
+	// resolved against. Empty means the working directory.
+	File string
+	// Modules loads and caches imported modules. Nil means a new loader for
+	// every evaluation.
+	Modules *ModuleLoader
+}
+
+type evaluator struct {
+	ctx  context.Context
+	opts Options
+	in   *bufio.Reader
+
+	// file and module are the script being evaluated and, inside an import,
+	// the module it defines
+	file   string
+	module *object.Module
+
+	steps  int64
+	allocs int64
+	depth  int
+}
+
+// EvalContext evaluates n in env, stopping with an error object as soon as ctx
+// is done or one of the budgets in opts runs out. The error's Kind tells the
+// reasons apart.
+func EvalContext(ctx context.Context, n ast.Node, env *object.Environment, opts Options) object.Object {
+	return newEvaluator(ctx, opts).eval(n, env)
+}
+
+// ApplyContext calls fn with args under the same rules as EvalContext. A
+// thrown error is returned as an *object.Error.
+func ApplyContext(ctx context.Context, fn object.Object, args []object.Object, env *object.Environment, opts Options) object.Object {
+	res := newEvaluator(ctx, opts).applyFunction(fn, args, env)
+	if exc, isExc := res.(*object.Exception); isExc {
+		return exc.Err
+	}
+	return res
+}
+
+func newEvaluator(ctx context.Context, opts Options) *evaluator {
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = DefaultMaxCallDepth
+	}
+	if opts.Builtins == nil {
+		opts.Builtins = defaultRegistry
+	}
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+	in := stdin
+	if opts.Stdin != nil {
+		in = bufio.NewReader(opts.Stdin)
+	}
+	if opts.Modules == nil {
+		opts.Modules = NewModuleLoader()
+	}
+	return &evaluator{ctx: ctx, opts: opts, in: in, file: opts.File}
+}
+
+// stdin buffers os.Stdin once for every evaluation that doesn't supply its
+// own input.
+var stdin = bufio.NewReader(os.Stdin)
+
+// tick accounts for one step of evaluation and reports whether the
+// evaluation has to stop.
+func (e *evaluator) tick() *object.Exception {
+	e.steps++
+	if e.opts.MaxSteps > 0 && e.steps > e.opts.MaxSteps {
+		return newError(object.BUDGET_ERROR, ErrStepBudget, e.opts.MaxSteps)
+	}
+
+	if err := e.ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return newError(object.TIMEOUT_ERROR, ErrTimeout)
+		}
+		return newError(object.CANCELED_ERROR, ErrCanceled)
+	}
+
+	return nil
+}
+
+// alloc accounts for the values in obj that were just created.
+func (e *evaluator) alloc(obj object.Object) *object.Exception {
+	switch obj := obj.(type) {
+	case *object.Array:
+		e.allocs += int64(len(obj.Elems)) + 1
+	case *object.String:
+		e.allocs += int64(len(obj.Value)) + 1
+	default:
+		return nil
+	}
+
+	if e.opts.MaxAllocs > 0 && e.allocs > e.opts.MaxAllocs {
+		return newError(object.BUDGET_ERROR, ErrAllocBudget, e.opts.MaxAllocs)
+	}
+
+	return nil
+}
+
+// reserve checks that the builtin name may build a string of size bytes
+// before it does. Alloc only charges for a string once it exists, which is
+// too late for one that doesn't fit in memory.
+func reserve(c object.CallContext, name string, size int64) *object.Exception {
+	if size < 0 || size > MaxStringSize {
+		return newError(object.ARGUMENT_ERROR, ErrResultTooLarge, name)
+	}
+
+	cc, isEval := c.(*callContext)
+	if isEval && cc.e.opts.MaxAllocs > 0 && cc.e.allocs+size+1 > cc.e.opts.MaxAllocs {
+		return newError(object.BUDGET_ERROR, ErrAllocBudget, cc.e.opts.MaxAllocs)
+	}
+
+	return nil
+}
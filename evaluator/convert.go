@@ -0,0 +1,643 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrCannotConvertToObject     = "cannot convert %s to a basedlang value"
+	ErrCannotConvertFromObject   = "cannot convert %s (%s) to %s"
+	ErrIntegerOverflow           = "%s overflows %s"
+	ErrCyclicConversion          = "cannot convert %s (%s) to %s, it contains itself"
+	ErrCyclicGoValue             = "cannot convert %s to a basedlang value, it contains itself"
+	ErrNotAGoFunction            = "not a function: %T"
+	ErrUnsupportedResults        = "unsupported results for %s: want at most one value optionally followed by an error"
+	ErrWrongNumberOfArgsVariadic = "wrong number of arguments. got=%d, want>=%d"
+	ErrInvalidConvertedArg       = "invalid argument: argument %d: %s"
+	ErrInvalidResult             = "invalid result: %s"
+	ErrHostPanic                 = "host function panicked: %v"
+)
+
+// structTag names the tag that renames or (with "-") hides struct fields.
+const structTag = "based"
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObject converts a Go value to a basedlang value. Integers, whole floats,
+// strings and bools map to scalars, slices and arrays to arrays, maps and
+// structs to hashes and functions to builtins. Nil maps to null.
+func ToObject(v any) (object.Object, error) {
+	return toObject(reflect.ValueOf(v))
+}
+
+func toObject(v reflect.Value) (object.Object, error) {
+	return (&goConverter{seen: map[goVisit]bool{}}).convert(v)
+}
+
+// goConverter converts one value with ToObject. seen holds the pointers, maps
+// and slices being converted, to catch cycles.
+type goConverter struct {
+	seen map[goVisit]bool
+}
+
+// goVisit identifies a pointer, map or slice. Slices also need their length,
+// since slices of different lengths can share a first element.
+type goVisit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter marks v as being converted. It fails if v already is, since
+// converting it again would never end. The returned func unmarks v.
+func (c *goConverter) enter(v reflect.Value) (func(), error) {
+	visit := goVisit{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		visit.len = v.Len()
+	}
+	if c.seen[visit] {
+		return nil, fmt.Errorf(ErrCyclicGoValue, v.Type())
+	}
+	c.seen[visit] = true
+	return func() { delete(c.seen, visit) }, nil
+}
+
+func (c *goConverter) convert(v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return NULL, nil
+		}
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return nativeBoolToObjBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf(ErrIntegerOverflow, fmt.Sprint(v.Uint()), object.INTEGER)
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		// basedlang has no floating point type, so only whole numbers fit
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, fmt.Errorf(ErrCannotConvertToObject, fmt.Sprintf("%v (%s)", f, v.Type()))
+		}
+		return &object.Integer{Value: int64(f)}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return NULL, nil
+			}
+			leave, err := c.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		arr := &object.Array{Elems: make([]object.Object, v.Len())}
+		for i := range arr.Elems {
+			elem, err := c.convert(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr.Elems[i] = elem
+		}
+		return arr, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		leave, err := c.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return c.mapToHash(v)
+	case reflect.Struct:
+		return c.structToHash(v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		if v.Kind() == reflect.Pointer {
+			leave, err := c.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return c.convert(v.Elem())
+	case reflect.Func:
+		if v.IsNil() {
+			return NULL, nil
+		}
+		return NewBuiltin(v.Interface())
+	default:
+		return nil, fmt.Errorf(ErrCannotConvertToObject, v.Type())
+	}
+}
+
+func (c *goConverter) mapToHash(v reflect.Value) (object.Object, error) {
+	hash := object.NewHash()
+
+	// Go maps are unordered, sort the keys so conversions are repeatable
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CanInt() && keys[j].CanInt() {
+			return keys[i].Int() < keys[j].Int()
+		}
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	for _, k := range keys {
+		key, err := c.convert(k)
+		if err != nil {
+			return nil, err
+		}
+		hashable, isHashable := key.(object.Hashable)
+		if !isHashable {
+			return nil, fmt.Errorf(ErrUnhashableKey, key.Inspect(), key.Type())
+		}
+		val, err := c.convert(v.MapIndex(k))
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(hashable, val)
+	}
+
+	return hash, nil
+}
+
+func (c *goConverter) structToHash(v reflect.Value) (object.Object, error) {
+	hash := object.NewHash()
+
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := fieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		val, err := c.convert(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		hash.Set(&object.String{Value: name}, val)
+	}
+
+	return hash, nil
+}
+
+// fieldName returns the hash key used for f, and false for fields that are
+// unexported or hidden with a `based:"-"` tag.
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	switch tag := f.Tag.Get(structTag); tag {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	default:
+		return tag, true
+	}
+}
+
+// FromObject converts obj to a Go value of type t. It is the inverse of
+// ToObject. Converting to an empty interface picks int64, string, bool, nil,
+// []any and map[string]any (map[any]any for hashes with non-string keys).
+//
+// Functions converted this way run without cancellation or budgets, use them
+// only for code that can be trusted to finish.
+func FromObject(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	call := func(fn object.Object, args ...object.Object) object.Object {
+		return ApplyContext(context.Background(), fn, args, object.NewEnvironment(), Options{})
+	}
+	return fromObject(obj, t, call)
+}
+
+// caller calls a basedlang function on behalf of a converted Go function.
+type caller func(fn object.Object, args ...object.Object) object.Object
+
+func fromObject(obj object.Object, t reflect.Type, call caller) (reflect.Value, error) {
+	c := &converter{call: call, seen: map[object.Object]bool{}}
+	return c.from(obj, t)
+}
+
+// converter converts one value with FromObject. seen holds the arrays and
+// hashes being converted, to catch cycles.
+type converter struct {
+	call caller
+	seen map[object.Object]bool
+}
+
+// enter marks obj as being converted. It fails if obj already is, since
+// converting it again would never end.
+func (c *converter) enter(obj object.Object, t reflect.Type) error {
+	if c.seen[obj] {
+		return fmt.Errorf(ErrCyclicConversion, obj.Inspect(), obj.Type(), t)
+	}
+	c.seen[obj] = true
+	return nil
+}
+
+func (c *converter) from(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if obj == nil {
+		obj = NULL
+	}
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		return c.fromAny(obj, t)
+	}
+	if reflect.TypeOf(obj).AssignableTo(t) {
+		return reflect.ValueOf(obj), nil
+	}
+
+	if obj == NULL {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+	}
+
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf(ErrCannotConvertFromObject, obj.Inspect(), obj.Type(), t)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, isBool := obj.(*object.Boolean)
+		if !isBool {
+			return fail()
+		}
+		return reflect.ValueOf(b.Value).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, isInt := obj.(*object.Integer)
+		if !isInt {
+			return fail()
+		}
+		v := reflect.New(t).Elem()
+		if v.OverflowInt(i.Value) {
+			return reflect.Value{}, fmt.Errorf(ErrIntegerOverflow, i.Inspect(), t)
+		}
+		v.SetInt(i.Value)
+		return v, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, isInt := obj.(*object.Integer)
+		if !isInt {
+			return fail()
+		}
+		v := reflect.New(t).Elem()
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return reflect.Value{}, fmt.Errorf(ErrIntegerOverflow, i.Inspect(), t)
+		}
+		v.SetUint(uint64(i.Value))
+		return v, nil
+	case reflect.Float32, reflect.Float64:
+		i, isInt := obj.(*object.Integer)
+		if !isInt {
+			return fail()
+		}
+		return reflect.ValueOf(float64(i.Value)).Convert(t), nil
+	case reflect.String:
+		s, isStr := obj.(*object.String)
+		if !isStr {
+			return fail()
+		}
+		return reflect.ValueOf(s.Value).Convert(t), nil
+	case reflect.Slice, reflect.Array:
+		arr, isArr := obj.(*object.Array)
+		if !isArr {
+			return fail()
+		}
+		if err := c.enter(obj, t); err != nil {
+			return reflect.Value{}, err
+		}
+		defer delete(c.seen, obj)
+		var v reflect.Value
+		if t.Kind() == reflect.Slice {
+			v = reflect.MakeSlice(t, len(arr.Elems), len(arr.Elems))
+		} else if t.Len() == len(arr.Elems) {
+			v = reflect.New(t).Elem()
+		} else {
+			return fail()
+		}
+		for i, elem := range arr.Elems {
+			ev, err := c.from(elem, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Index(i).Set(ev)
+		}
+		return v, nil
+	case reflect.Map:
+		hash, isHash := obj.(*object.Hash)
+		if !isHash {
+			return fail()
+		}
+		if err := c.enter(obj, t); err != nil {
+			return reflect.Value{}, err
+		}
+		defer delete(c.seen, obj)
+		v := reflect.MakeMapWithSize(t, len(hash.Keys))
+		for _, hk := range hash.Keys {
+			pair := hash.Pairs[hk]
+			kv, err := c.from(pair.Key, t.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			vv, err := c.from(pair.Value, t.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.SetMapIndex(kv, vv)
+		}
+		return v, nil
+	case reflect.Struct:
+		hash, isHash := obj.(*object.Hash)
+		if !isHash {
+			return fail()
+		}
+		if err := c.enter(obj, t); err != nil {
+			return reflect.Value{}, err
+		}
+		defer delete(c.seen, obj)
+		v := reflect.New(t).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			name, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			val, exists := hash.Get(&object.String{Value: name})
+			if !exists {
+				continue
+			}
+			fv, err := c.from(val, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Field(i).Set(fv)
+		}
+		return v, nil
+	case reflect.Pointer:
+		ev, err := c.from(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v := reflect.New(t.Elem())
+		v.Elem().Set(ev)
+		return v, nil
+	case reflect.Func:
+		if obj.Type() != object.FUNCTION && obj.Type() != object.BUILTIN {
+			return fail()
+		}
+		return goFunc(obj, t, c.call)
+	default:
+		return fail()
+	}
+}
+
+func (c *converter) fromAny(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	var val any
+
+	if obj.Type() == object.ARRAY || obj.Type() == object.HASH {
+		if err := c.enter(obj, t); err != nil {
+			return reflect.Value{}, err
+		}
+		defer delete(c.seen, obj)
+	}
+
+	switch obj := obj.(type) {
+	case *object.Integer:
+		val = obj.Value
+	case *object.String:
+		val = obj.Value
+	case *object.Boolean:
+		val = obj.Value
+	case *object.Null:
+		return reflect.Zero(t), nil
+	case *object.Array:
+		elems := make([]any, len(obj.Elems))
+		for i, elem := range obj.Elems {
+			ev, err := c.fromAny(elem, t)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			elems[i] = ev.Interface()
+		}
+		val = elems
+	case *object.Hash:
+		keys := make([]any, len(obj.Keys))
+		vals := make([]any, len(obj.Keys))
+		stringKeys := true
+		for i, hk := range obj.Keys {
+			pair := obj.Pairs[hk]
+			kv, err := c.fromAny(pair.Key, t)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			vv, err := c.fromAny(pair.Value, t)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			keys[i] = kv.Interface()
+			vals[i] = vv.Interface()
+			if _, isStr := keys[i].(string); !isStr {
+				stringKeys = false
+			}
+		}
+		if stringKeys {
+			m := make(map[string]any, len(keys))
+			for i, k := range keys {
+				m[k.(string)] = vals[i]
+			}
+			val = m
+		} else {
+			m := make(map[any]any, len(keys))
+			for i, k := range keys {
+				m[k] = vals[i]
+			}
+			val = m
+		}
+	default:
+		// Functions and errors have no closer Go equivalent
+		val = obj
+	}
+
+	v := reflect.New(t).Elem()
+	v.Set(reflect.ValueOf(val))
+	return v, nil
+}
+
+// NewBuiltin wraps a Go function as a basedlang builtin. Arguments are
+// converted with FromObject and checked against the function's parameter
+// types, including variadic ones. The function may return nothing, a value,
+// an error, or a value and an error. A non-nil error is thrown as an error of
+// kind HOST_ERROR unless it already is an *object.Error.
+func NewBuiltin(fn any) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf(ErrNotAGoFunction, fn)
+	}
+	if !supportedResults(v.Type()) {
+		return nil, fmt.Errorf(ErrUnsupportedResults, v.Type())
+	}
+
+	return &object.Builtin{
+		Fn: func(c object.CallContext, args ...object.Object) object.Object {
+			return callGoFunction(c, v, args)
+		},
+	}, nil
+}
+
+func supportedResults(t reflect.Type) bool {
+	switch t.NumOut() {
+	case 0, 1:
+		return true
+	case 2:
+		return t.Out(1) == errorType
+	default:
+		return false
+	}
+}
+
+func callGoFunction(c object.CallContext, fn reflect.Value, args []object.Object) (res object.Object) {
+	t := fn.Type()
+
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsVariadic, len(args), fixed)
+		}
+	} else if len(args) != fixed {
+		return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgs, len(args), fixed)
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var pt reflect.Type
+		if i < fixed {
+			pt = t.In(i)
+		} else {
+			pt = t.In(fixed).Elem()
+		}
+		v, err := fromObject(arg, pt, c.Call)
+		if err != nil {
+			return newError(object.ARGUMENT_ERROR, ErrInvalidConvertedArg, i+1, err)
+		}
+		in[i] = v
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			res = newError(object.HOST_ERROR, ErrHostPanic, r)
+		}
+	}()
+
+	out := fn.Call(in)
+
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return throwGoError(err)
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return NULL
+	}
+
+	obj, err := toObject(out[0])
+	if err != nil {
+		return newError(object.HOST_ERROR, ErrInvalidResult, err)
+	}
+	return obj
+}
+
+func throwGoError(err error) *object.Exception {
+	var errObj *object.Error
+	if errors.As(err, &errObj) {
+		return throw(errObj)
+	}
+	return newError(object.HOST_ERROR, "%s", err)
+}
+
+// goFunc makes a Go function of type t that calls the basedlang function fn.
+// Failures are returned through a trailing error result when t has one and
+// panic otherwise.
+func goFunc(fn object.Object, t reflect.Type, call caller) (reflect.Value, error) {
+	if !supportedResults(t) {
+		return reflect.Value{}, fmt.Errorf(ErrUnsupportedResults, t)
+	}
+
+	returnsErr := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+
+	fail := func(err error) []reflect.Value {
+		if !returnsErr {
+			panic(err)
+		}
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		out[len(out)-1] = reflect.ValueOf(&err).Elem()
+		return out
+	}
+
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		if t.IsVariadic() {
+			last := in[len(in)-1]
+			in = in[:len(in)-1]
+			for i := 0; i < last.Len(); i++ {
+				in = append(in, last.Index(i))
+			}
+		}
+
+		args := make([]object.Object, len(in))
+		for i, v := range in {
+			arg, err := toObject(v)
+			if err != nil {
+				return fail(err)
+			}
+			args[i] = arg
+		}
+
+		res := call(fn, args...)
+		if exc, isExc := res.(*object.Exception); isExc {
+			return fail(exc.Err)
+		}
+		if errObj, isErr := res.(*object.Error); isErr {
+			return fail(errObj)
+		}
+
+		out := make([]reflect.Value, 0, t.NumOut())
+		if t.NumOut() > 0 && t.Out(0) != errorType {
+			v, err := fromObject(res, t.Out(0), call)
+			if err != nil {
+				return fail(err)
+			}
+			out = append(out, v)
+		}
+		if returnsErr {
+			out = append(out, reflect.Zero(errorType))
+		}
+		return out
+	}), nil
+}
@@ -0,0 +1,208 @@
+package evaluator
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+type convertUser struct {
+	Name    string
+	Age     int `based:"age"`
+	Tags    []string
+	private int
+	Skipped bool `based:"-"`
+}
+
+func TestToObject(t *testing.T) {
+	tests := []struct {
+		input    any
+		expected string
+	}{
+		{nil, "null"},
+		{42, "42"},
+		{uint8(7), "7"},
+		{3.0, "3"},
+		{"hi", "hi"},
+		{true, "true"},
+		{[]int{1, 2, 3}, "[1, 2, 3]"},
+		{[2]bool{true, false}, "[true, false]"},
+		{map[string]int{"b": 2, "a": 1}, "{a: 1, b: 2}"},
+		{map[int]string{10: "x", 9: "y"}, `{9: y, 10: x}`},
+		{convertUser{Name: "ann", Age: 30, Tags: []string{"x"}}, "{Name: ann, age: 30, Tags: [x]}"},
+		{&convertUser{Name: "bob"}, "{Name: bob, age: 0, Tags: null}"},
+		{[]any{1, "a", nil}, "[1, a, null]"},
+	}
+
+	for _, tc := range tests {
+		obj, err := ToObject(tc.input)
+		if err != nil {
+			t.Errorf("ToObject(%#v) returned error: %v", tc.input, err)
+			continue
+		}
+		if obj.Inspect() != tc.expected {
+			t.Errorf("ToObject(%#v) wrong. expected=%q, got=%q", tc.input, tc.expected, obj.Inspect())
+		}
+	}
+
+	for _, input := range []any{1.5, uint64(1 << 63), make(chan int)} {
+		if _, err := ToObject(input); err == nil {
+			t.Errorf("ToObject(%#v) should fail", input)
+		}
+	}
+
+	shared := &convertNode{Value: 1}
+	obj, err := ToObject([]*convertNode{shared, shared})
+	if err != nil || obj.Inspect() != "[{Value: 1, Next: null}, {Value: 1, Next: null}]" {
+		t.Errorf("ToObject of a shared pointer wrong. got=%v, %v", obj, err)
+	}
+
+	node := &convertNode{Value: 1}
+	node.Next = node
+	selfMap := map[string]any{}
+	selfMap["self"] = selfMap
+	selfSlice := []any{nil}
+	selfSlice[0] = selfSlice
+	for _, input := range []any{node, selfMap, selfSlice} {
+		if _, err := ToObject(input); err == nil || !strings.Contains(err.Error(), "it contains itself") {
+			t.Errorf("ToObject of a value that contains itself should fail. got=%v", err)
+		}
+	}
+}
+
+type convertNode struct {
+	Value int
+	Next  *convertNode
+}
+
+func TestFromObject(t *testing.T) {
+	user := object.NewHash()
+	user.Set(&object.String{Value: "Name"}, &object.String{Value: "ann"})
+	user.Set(&object.String{Value: "age"}, &object.Integer{Value: 30})
+	user.Set(&object.String{Value: "Tags"}, &object.Array{Elems: []object.Object{&object.String{Value: "x"}}})
+
+	tests := []struct {
+		input    object.Object
+		expected any
+	}{
+		{&object.Integer{Value: 5}, int(5)},
+		{&object.Integer{Value: 5}, uint16(5)},
+		{&object.Integer{Value: 5}, float64(5)},
+		{&object.String{Value: "s"}, "s"},
+		{TRUE, true},
+		{&object.Array{Elems: []object.Object{&object.Integer{Value: 1}}}, []int{1}},
+		{user, convertUser{Name: "ann", Age: 30, Tags: []string{"x"}}},
+		{user, map[string]any{"Name": "ann", "age": int64(30), "Tags": []any{"x"}}},
+		{NULL, []int(nil)},
+		{&object.Integer{Value: 5}, any(int64(5))},
+		{&object.Array{Elems: []object.Object{&object.Integer{Value: 1}}}, &[]int{1}},
+	}
+
+	for _, tc := range tests {
+		v, err := FromObject(tc.input, reflect.TypeOf(tc.expected))
+		if err != nil {
+			t.Errorf("FromObject(%s) returned error: %v", tc.input.Inspect(), err)
+			continue
+		}
+		if !reflect.DeepEqual(v.Interface(), tc.expected) {
+			t.Errorf("FromObject(%s) wrong. expected=%#v, got=%#v", tc.input.Inspect(), tc.expected, v.Interface())
+		}
+	}
+
+	cyclic := &object.Array{Elems: []object.Object{NULL}}
+	cyclic.Elems[0] = cyclic
+
+	failing := []struct {
+		input object.Object
+		t     reflect.Type
+	}{
+		{&object.Integer{Value: 300}, reflect.TypeOf(int8(0))},
+		{&object.Integer{Value: -1}, reflect.TypeOf(uint(0))},
+		{&object.String{Value: "s"}, reflect.TypeOf(0)},
+		{NULL, reflect.TypeOf(0)},
+		{cyclic, reflect.TypeOf([]any{})},
+		{cyclic, reflect.TypeOf((*any)(nil)).Elem()},
+	}
+	for _, tc := range failing {
+		if _, err := FromObject(tc.input, tc.t); err == nil {
+			t.Errorf("FromObject(%s, %s) should fail", tc.input.Inspect(), tc.t)
+		}
+	}
+}
+
+func TestNewBuiltin(t *testing.T) {
+	goFns := map[string]any{
+		"shout": func(s string, n int) (string, error) {
+			if n < 0 {
+				return "", errors.New("n must not be negative")
+			}
+			return strings.Repeat(s, n) + "!", nil
+		},
+		"sum": func(xs ...int) int {
+			total := 0
+			for _, x := range xs {
+				total += x
+			}
+			return total
+		},
+		"explode": func() { panic("boom") },
+		"apply":   func(f func(int) int, x int) int { return f(x) },
+	}
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`shout("ab", 2)`, "abab!"},
+		{`shout("ab", -1)`, "HostError: n must not be negative"},
+		{`shout("ab")`, "ArgumentError: wrong number of arguments. got=1, want=2"},
+		{`shout(1, 2)`, "ArgumentError: invalid argument: argument 1: cannot convert 1 (INTEGER) to string"},
+		{`sum()`, 0},
+		{`sum(1, 2, 3)`, 6},
+		{`explode()`, "HostError: host function panicked: boom"},
+		{`apply(fn(x) { x * 10 }, 4)`, 40},
+		{`try { shout("ab", -1) } catch (e) { e["kind"] }`, "HostError"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		for name, fn := range goFns {
+			builtin, err := NewBuiltin(fn)
+			if err != nil {
+				t.Fatalf("NewBuiltin(%s) returned error: %v", name, err)
+			}
+			env.Set(name, builtin)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			var got string
+			switch obj := evaluated.(type) {
+			case *object.String:
+				got = obj.Value
+			case *object.Error:
+				got = obj.Error()
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if got != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, expected, got)
+			}
+		}
+	}
+
+	for _, fn := range []any{42, func() (int, int) { return 0, 0 }} {
+		if _, err := NewBuiltin(fn); err == nil {
+			t.Errorf("NewBuiltin(%T) should fail", fn)
+		}
+	}
+}
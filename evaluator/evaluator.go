@@ -1,113 +1,211 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
 	"github.com/nayyara-airlangga/basedlang/object"
 )
 
 const (
-	ErrUnsupportedOperatorInfix  = "unsupported operator: %s %s %s"
-	ErrUnsupportedOperatorPrefix = "unsupported operator: %s%s"
-	ErrUnsupportedOperatorIndex  = "unsupported operator: index not supported on %s (%s)"
-	ErrInvalidIndex              = "invalid argument: index %s (%s) is not an integer"
-	ErrTypeMismatch              = "type mismatch: %s %s %s"
-	ErrIdentifierNotFound        = "identifier not found: %s"
-	ErrNotAFunction              = "not a function: %s"
-	ErrWrongNumberOfArgs         = "wrong number of arguments. got=%d, want=%d"
+	ErrUnsupportedOperatorInfix   = "unsupported operator: %s %s %s"
+	ErrUnsupportedOperatorPrefix  = "unsupported operator: %s%s"
+	ErrUnsupportedOperatorPostfix = "unsupported operator: %s%s"
+	ErrUnsupportedOperatorIndex   = "unsupported operator: index not supported on %s (%s)"
+	ErrInvalidIndex               = "invalid argument: index %s (%s) is not an integer"
+	ErrUnsupportedOperatorSlice   = "unsupported operator: slice not supported on %s (%s)"
+	ErrInvalidSliceBound          = "invalid argument: slice bound %s (%s) is not an integer"
+	ErrZeroSliceStep              = "invalid argument: slice step cannot be zero"
+	ErrUnhashableKey              = "invalid argument: %s (%s) can't be used as a hash key"
+	ErrTypeMismatch               = "type mismatch: %s %s %s"
+	ErrDivisionByZero             = "division by zero: %d / 0"
+	ErrIdentifierNotFound         = "identifier not found: %s"
+	ErrNotAFunction               = "not a function: %s"
+	ErrWrongNumberOfArgs          = "wrong number of arguments. got=%d, want=%d"
+	ErrWrongNumberOfArgsTo        = "wrong number of arguments to %s. got=%d, want%s"
+	ErrInvalidSpread              = "invalid argument: %s (%s) can't be spread, want an array"
+	ErrNamedArgsUnsupported       = "invalid argument: %s doesn't accept named arguments"
+	ErrUnknownNamedArg            = "invalid argument: %s has no parameter %s"
+	ErrDuplicateArg               = "invalid argument: %s got multiple values for %s"
+	ErrMissingArg                 = "invalid argument: %s is missing an argument for %s"
+	ErrConstReassign              = "cannot reassign constant %s"
+	ErrConstRedeclare             = "cannot declare constant %s, it is already declared in this scope"
 )
 
-func newError(format string, args ...any) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, args...)}
+// newError creates an error of the given kind and throws it.
+func newError(kind object.ErrorKind, format string, args ...any) *object.Exception {
+	return &object.Exception{Err: &object.Error{Kind: kind, Message: fmt.Sprintf(format, args...)}}
 }
 
-func isError(obj object.Object) bool {
-	return obj != nil && obj.Type() == object.ERROR
+// isUnwinding reports whether obj has to be passed up the stack instead of
+// being used as a value: a thrown error or an early return from the ?
+// operator. Caught errors are ordinary values.
+func isUnwinding(obj object.Object) bool {
+	return obj != nil && (obj.Type() == object.EXCEPTION || obj.Type() == object.RETURN_VALUE)
 }
 
+// maxFrameArgLen is how much of each argument's Inspect output is kept in a
+// stack frame.
+const maxFrameArgLen = 32
+
 var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
 )
 
+// Eval evaluates n in env with no cancellation and no budgets other than the
+// default call depth limit.
 func Eval(n ast.Node, env *object.Environment) object.Object {
+	return EvalContext(context.Background(), n, env, Options{})
+}
+
+func (e *evaluator) eval(n ast.Node, env *object.Environment) object.Object {
 	switch n := n.(type) {
 	// Statements
 	case *ast.Program:
-		return evalProgram(n.Statements, env)
+		return e.evalProgram(n.Statements, env)
 	case *ast.LetStatement:
-		val := Eval(n.Value, env)
-		if isError(val) {
-			return val
-		}
-		env.Set(n.Name.Value, val)
+		return e.evalLetStatement(n, env)
 	case *ast.ExpressionStatement:
-		return Eval(n.Expression, env)
+		return e.eval(n.Expression, env)
 	case *ast.BlockStatement:
-		return evalBlockStatements(n.Statements, env)
+		return e.evalBlockStatements(n.Statements, env)
 	case *ast.ReturnStatement:
-		val := Eval(n.ReturnValue, env)
-		if isError(val) {
+		val := e.eval(n.ReturnValue, env)
+		if isUnwinding(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
+	case *ast.ImportStatement:
+		mod := e.importModule(n.Path)
+		if isUnwinding(mod) {
+			return mod
+		}
+		if err := declare(env, n.Alias.Value, mod, false); err != nil {
+			return err
+		}
+	case *ast.ExportStatement:
+		if n.Fn != nil {
+			if res := e.eval(n.Fn, env); isUnwinding(res) {
+				return res
+			}
+			if e.module != nil {
+				e.module.Exports[n.Fn.Function.Name.Value], _ = env.Get(n.Fn.Function.Name.Value)
+			}
+			return nil
+		}
+		if res := e.eval(n.Let, env); isUnwinding(res) {
+			return res
+		}
+		if e.module != nil {
+			names := []string{}
+			if n.Let.Pattern != nil {
+				names = ast.PatternNames(n.Let.Pattern)
+			} else {
+				names = append(names, n.Let.Name.Value)
+			}
+			for _, name := range names {
+				e.module.Exports[name], _ = env.Get(name)
+			}
+		}
+	case *ast.FunctionStatement:
+		if err := declare(env, n.Function.Name.Value, evalFunctionLiteral(n.Function, env), false); err != nil {
+			return err
+		}
+	case *ast.StructStatement:
+		st := &object.StructType{Name: n.Name.Value}
+		for _, field := range n.Fields {
+			st.Fields = append(st.Fields, field.Value)
+		}
+		if err := declare(env, n.Name.Value, st, false); err != nil {
+			return err
+		}
+	case *ast.ThrowStatement:
+		val := e.eval(n.Value, env)
+		if isUnwinding(val) {
+			return val
+		}
+		return throw(val)
 		// Expressions
 	case *ast.Identifier:
-		return evalIdentifier(n, env)
+		return e.evalIdentifier(n, env)
 	case *ast.IntLiteral:
 		return &object.Integer{Value: n.Value}
 	case *ast.BooleanLiteral:
 		return nativeBoolToObjBool(n.Value)
 	case *ast.StringLiteral:
 		return &object.String{Value: n.Value}
+	case *ast.RegexLiteral:
+		return &object.Regex{Regexp: n.Regexp}
 	case *ast.ArrayLiteral:
-		elems := evalExpressions(n.Elems, env)
-		if len(elems) == 1 && isError(elems[0]) {
+		elems := e.evalExpressions(n.Elems, env)
+		if len(elems) == 1 && isUnwinding(elems[0]) {
 			return elems[0]
 		}
-		return &object.Array{Elems: elems}
-	case *ast.IndexExpression:
-		left := Eval(n.Left, env)
-		if isError(left) {
-			return left
-		}
-		idx := Eval(n.Index, env)
-		if isError(idx) {
-			return idx
+		arr := &object.Array{Elems: elems}
+		if err := e.alloc(arr); err != nil {
+			return err
 		}
-		return evalIndexExpression(left, idx)
+		return arr
+	case *ast.IndexExpression, *ast.MemberExpression, *ast.SliceExpression, *ast.CallExpression:
+		res, _ := e.evalChain(n.(ast.Expression), env)
+		return res
+	case *ast.AssignExpression:
+		return e.evalAssignExpression(n, env)
 	case *ast.PrefixExpression:
-		right := Eval(n.Right, env)
-		if isError(right) {
+		right := e.eval(n.Right, env)
+		if isUnwinding(right) {
 			return right
 		}
 		return evalPrefixExpression(n.Operator, right)
+	case *ast.PostfixExpression:
+		left := e.eval(n.Left, env)
+		if isUnwinding(left) {
+			return left
+		}
+		return evalPostfixExpression(n.Operator, left)
 	case *ast.InfixExpression:
-		left := Eval(n.Left, env)
-		if isError(left) {
+		left := e.eval(n.Left, env)
+		if isUnwinding(left) {
 			return left
 		}
-		right := Eval(n.Right, env)
-		if isError(right) {
+		// ?? only evaluates its right side when the left side is null
+		if n.Operator == "??" {
+			if left != NULL {
+				return left
+			}
+			return e.eval(n.Right, env)
+		}
+		right := e.eval(n.Right, env)
+		if isUnwinding(right) {
 			return right
 		}
-		return evalInfixExpression(n.Operator, left, right)
+		res := evalInfixExpression(n.Operator, left, right)
+		if err := e.alloc(res); err != nil {
+			return err
+		}
+		return res
 	case *ast.IfExpression:
-		return evalIfExpression(n, env)
-	case *ast.FunctionLiteral:
-		return &object.Function{Params: n.Params, Body: n.Body, Env: env}
-	case *ast.CallExpression:
-		f := Eval(n.Function, env)
-		if isError(f) {
-			return f
+		return e.evalIfExpression(n, env)
+	case *ast.ConditionalExpression:
+		cond := e.eval(n.Condition, env)
+		if isUnwinding(cond) {
+			return cond
 		}
-		args := evalExpressions(n.Args, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		if isTruthy(cond) {
+			return e.eval(n.Consequence, env)
 		}
-		return applyFunction(f, args)
+		return e.eval(n.Alternative, env)
+	case *ast.TryExpression:
+		return e.evalTryExpression(n, env)
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(n, env)
+	case *ast.FunctionLiteral:
+		return evalFunctionLiteral(n, env)
 	default:
 		return NULL
 	}
@@ -115,36 +213,322 @@ func Eval(n ast.Node, env *object.Environment) object.Object {
 	return nil
 }
 
-func applyFunction(f object.Object, args []object.Object) object.Object {
+// evalLetStatement binds the value of a let or const statement. A destructuring
+// let binds nothing unless the whole pattern matches.
+func (e *evaluator) evalLetStatement(n *ast.LetStatement, env *object.Environment) object.Object {
+	val := e.eval(n.Value, env)
+	if isUnwinding(val) {
+		return val
+	}
+
+	if n.Pattern == nil {
+		if fn, isFunc := val.(*object.Function); isFunc && fn.Name == "" {
+			fn.Name = n.Name.Value
+		}
+		if err := declare(env, n.Name.Value, val, n.IsConst()); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	matched := object.NewEnvironment()
+	if err := e.destructure(n.Pattern, val, matched, n.TokenLiteral()+" "+n.Pattern.String()); err != nil {
+		return err
+	}
+	for _, name := range ast.PatternNames(n.Pattern) {
+		val, _ := matched.Get(name)
+		if err := declare(env, name, val, n.IsConst()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// declare binds name in env's own scope. Constants can't be declared over,
+// and a constant can't be declared over anything else in its scope.
+func declare(env *object.Environment, name string, val object.Object, constant bool) *object.Exception {
+	switch {
+	case env.IsConst(name):
+		return newError(object.NAME_ERROR, ErrConstReassign, name)
+	case constant && env.Declared(name):
+		return newError(object.NAME_ERROR, ErrConstRedeclare, name)
+	case constant:
+		env.SetConst(name, val)
+	default:
+		env.Set(name, val)
+	}
+	return nil
+}
+
+// evalChain evaluates a member, index, slice or call expression. skipped
+// reports that an optional link (?. or ?.[) somewhere in the chain found null,
+// in which case the rest of the chain isn't evaluated and its value is null.
+func (e *evaluator) evalChain(n ast.Expression, env *object.Environment) (res object.Object, skipped bool) {
+	var obj object.Object
+	optional := false
+	switch n := n.(type) {
+	case *ast.MemberExpression:
+		obj, skipped = e.evalChain(n.Object, env)
+		optional = n.Optional
+	case *ast.IndexExpression:
+		obj, skipped = e.evalChain(n.Left, env)
+		optional = n.Optional
+	case *ast.SliceExpression:
+		obj, skipped = e.evalChain(n.Left, env)
+		optional = n.Optional
+	case *ast.CallExpression:
+		obj, skipped = e.evalChain(n.Function, env)
+	default:
+		return e.eval(n, env), false
+	}
+
+	if skipped || isUnwinding(obj) {
+		return obj, skipped
+	}
+	if optional && obj == NULL {
+		return NULL, true
+	}
+
+	switch n := n.(type) {
+	case *ast.MemberExpression:
+		return evalMember(obj, n.Property.Value), false
+	case *ast.IndexExpression:
+		idx := e.eval(n.Index, env)
+		if isUnwinding(idx) {
+			return idx, false
+		}
+		return evalIndexExpression(obj, idx), false
+	case *ast.SliceExpression:
+		res := e.evalSliceExpression(n, obj, env)
+		if err := e.alloc(res); err != nil {
+			return err, false
+		}
+		return res, false
+	default:
+		return e.evalCallExpression(n.(*ast.CallExpression), obj, env), false
+	}
+}
+
+func (e *evaluator) evalCallExpression(n *ast.CallExpression, f object.Object, env *object.Environment) object.Object {
+	args, named, err := e.evalArguments(n.Args, env)
+	if err != nil {
+		return err
+	}
+	res := e.applyFunctionNamed(f, args, named, env)
+	if exc, isExc := res.(*object.Exception); isExc {
+		if fn, isFunc := f.(*object.Function); isFunc {
+			exc.Err.Frames = append(exc.Err.Frames, newFrame(n, fn, args))
+		}
+	}
+	return res
+}
+
+// evalFunctionLiteral creates a closure over env. A named function gets a scope
+// of its own holding its name, so it can recurse even if the name is rebound
+// outside.
+func evalFunctionLiteral(fl *ast.FunctionLiteral, env *object.Environment) *object.Function {
+	fn := &object.Function{Params: fl.Params, Rest: fl.Rest, Body: fl.Body, Env: env}
+	if fl.Name != nil {
+		fn.Name = fl.Name.Value
+		fn.Env = object.NewLocalEnvironment(env)
+		fn.Env.Set(fn.Name, fn)
+	}
+	return fn
+}
+
+// applyFunction calls f with args. env is the environment of the call site,
+// which builtins can see through their call context.
+func (e *evaluator) applyFunction(f object.Object, args []object.Object, env *object.Environment) object.Object {
+	return e.applyFunctionNamed(f, args, nil, env)
+}
+
+// applyFunctionNamed is applyFunction with named arguments, which only
+// basedlang functions accept.
+func (e *evaluator) applyFunctionNamed(f object.Object, args []object.Object, named []namedArg, env *object.Environment) object.Object {
+	if err := e.tick(); err != nil {
+		return err
+	}
+
+	if _, isFunc := f.(*object.Function); !isFunc && len(named) > 0 {
+		return newError(object.ARGUMENT_ERROR, ErrNamedArgsUnsupported, f.Type())
+	}
+
 	switch fn := f.(type) {
 	case *object.Function:
-		fun, isFunc := f.(*object.Function)
-		if !isFunc {
-			return newError(ErrNotAFunction, fun.Type())
+		if e.depth >= e.opts.MaxDepth {
+			return newError(object.STACK_OVERFLOW_ERROR, ErrMaxDepthExceeded, e.opts.MaxDepth)
 		}
-		if len(fn.Params) != len(args) {
-			return newError(ErrWrongNumberOfArgs, len(args), len(fn.Params))
+		e.depth++
+		defer func() { e.depth-- }()
+
+		extEnv, err := e.extendFunctionEnv(fn, args, named)
+		if err != nil {
+			return err
 		}
-		extEnv := extendFunctionEnv(fun, args)
-		evaluated := Eval(fun.Body, extEnv)
+		evaluated := e.eval(fn.Body, extEnv)
 		return unwrapReturnValue(evaluated)
+	case *object.StructType:
+		if len(fn.Fields) != len(args) {
+			return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsTo, fn.Name, len(args), fmt.Sprintf("=%d", len(fn.Fields)))
+		}
+		return &object.Struct{StructType: fn, Values: append([]object.Object(nil), args...)}
 	case *object.Builtin:
-		return fn.Fn(args...)
+		res := fn.Fn(&callContext{e: e, env: env}, args...)
+		if err := e.alloc(res); err != nil {
+			return err
+		}
+		return res
 	default:
-		return newError(ErrNotAFunction, f.Type())
+		return newError(object.TYPE_ERROR, ErrNotAFunction, f.Type())
+	}
+
+}
+
+func newFrame(call *ast.CallExpression, fn *object.Function, args []object.Object) object.Frame {
+	summary := make([]string, len(args))
+	for i, arg := range args {
+		summary[i] = strings.Join(strings.Fields(arg.Inspect()), " ")
+		if len(summary[i]) > maxFrameArgLen {
+			summary[i] = summary[i][:maxFrameArgLen] + "..."
+		}
 	}
 
+	return object.Frame{
+		Function: fn.Name,
+		Args:     strings.Join(summary, ", "),
+		Line:     call.Token.Line,
+		Column:   call.Token.Column,
+	}
 }
 
-func extendFunctionEnv(
+// extendFunctionEnv binds the arguments of a call to fn's parameters in a new
+// scope. Defaults are evaluated in that scope, so they can refer to the
+// parameters before them.
+func (e *evaluator) extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	named []namedArg,
+) (*object.Environment, object.Object) {
+	if len(args) > len(fn.Params) && fn.Rest == nil {
+		return nil, arityError(fn, len(args)+len(named))
+	}
+
+	vals := make([]object.Object, len(fn.Params))
+	copy(vals, args)
+
+	for _, arg := range named {
+		i := slices.IndexFunc(fn.Params, func(param *ast.Parameter) bool {
+			binding, isBinding := param.Pattern.(*ast.BindingPattern)
+			return isBinding && binding.Name.Value == arg.name
+		})
+		switch {
+		case i < 0:
+			return nil, newError(object.ARGUMENT_ERROR, ErrUnknownNamedArg, functionLabel(fn), arg.name)
+		case vals[i] != nil:
+			return nil, newError(object.ARGUMENT_ERROR, ErrDuplicateArg, functionLabel(fn), arg.name)
+		}
+		vals[i] = arg.value
+	}
+
 	env := object.NewLocalEnvironment(fn.Env)
-	for i, arg := range fn.Params {
-		env.Set(arg.Value, args[i])
+	for i, param := range fn.Params {
+		val := vals[i]
+		if val == nil {
+			switch {
+			case param.Default != nil:
+				if val = e.eval(param.Default, env); isUnwinding(val) {
+					return nil, val
+				}
+			case len(named) > 0:
+				return nil, newError(object.ARGUMENT_ERROR, ErrMissingArg, functionLabel(fn), param.Pattern.String())
+			default:
+				return nil, arityError(fn, len(args))
+			}
+		}
+		what := fmt.Sprintf("parameter %s of %s", param.Pattern, functionLabel(fn))
+		if err := e.destructure(param.Pattern, val, env, what); err != nil {
+			return nil, err
+		}
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Params) {
+			rest = append(rest, args[len(fn.Params):]...)
+		}
+		what := fmt.Sprintf("parameter ...%s of %s", fn.Rest, functionLabel(fn))
+		if err := e.destructure(fn.Rest, &object.Array{Elems: rest}, env, what); err != nil {
+			return nil, err
+		}
 	}
-	return env
+
+	return env, nil
+}
+
+// arityError reports a call to fn with the wrong number of arguments along
+// with the range fn accepts.
+func arityError(fn *object.Function, got int) *object.Exception {
+	min, max := fn.Arity()
+
+	want := fmt.Sprintf("=%d", min)
+	switch {
+	case max < 0:
+		want = fmt.Sprintf(">=%d", min)
+	case max > min:
+		want = fmt.Sprintf("=%d to %d", min, max)
+	}
+
+	return newError(object.ARGUMENT_ERROR, ErrWrongNumberOfArgsTo, functionLabel(fn), got, want)
+}
+
+// functionLabel names fn in error messages.
+func functionLabel(fn *object.Function) string {
+	if fn.Name == "" {
+		return "anonymous function"
+	}
+	return fn.Name
+}
+
+type namedArg struct {
+	name  string
+	value object.Object
+}
+
+// evalArguments evaluates the arguments of a call, flattening spread arrays
+// into the positional arguments.
+func (e *evaluator) evalArguments(exprs []ast.Expression, env *object.Environment) ([]object.Object, []namedArg, object.Object) {
+	args := []object.Object{}
+	named := []namedArg{}
+
+	for _, expr := range exprs {
+		switch expr := expr.(type) {
+		case *ast.SpreadExpression:
+			val := e.eval(expr.Value, env)
+			if isUnwinding(val) {
+				return nil, nil, val
+			}
+			arr, isArr := val.(*object.Array)
+			if !isArr {
+				return nil, nil, newError(object.TYPE_ERROR, ErrInvalidSpread, val.Inspect(), val.Type())
+			}
+			args = append(args, arr.Elems...)
+		case *ast.NamedArgument:
+			val := e.eval(expr.Value, env)
+			if isUnwinding(val) {
+				return nil, nil, val
+			}
+			named = append(named, namedArg{name: expr.Name.Value, value: val})
+		default:
+			val := e.eval(expr, env)
+			if isUnwinding(val) {
+				return nil, nil, val
+			}
+			args = append(args, val)
+		}
+	}
+
+	return args, named, nil
 }
 func unwrapReturnValue(obj object.Object) object.Object {
 	if rv, isRetVal := obj.(*object.ReturnValue); isRetVal {
@@ -153,10 +537,10 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func evalExpressions(exprs []ast.Expression, env *object.Environment) (result []object.Object) {
-	for _, e := range exprs {
-		evaluated := Eval(e, env)
-		if isError(evaluated) {
+func (e *evaluator) evalExpressions(exprs []ast.Expression, env *object.Environment) (result []object.Object) {
+	for _, expr := range exprs {
+		evaluated := e.eval(expr, env)
+		if isUnwinding(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -171,9 +555,20 @@ func evalIndexExpression(left, idx object.Object) object.Object {
 		if idx.Type() == object.INTEGER {
 			return evalArrayIndexExpression(left, idx)
 		}
-		return newError(ErrInvalidIndex, idx.Inspect(), idx.Type())
+		return newError(object.INDEX_ERROR, ErrInvalidIndex, idx.Inspect(), idx.Type())
+	case left.Type() == object.STRING:
+		if idx.Type() == object.INTEGER {
+			return evalStringIndexExpression(left, idx)
+		}
+		return newError(object.INDEX_ERROR, ErrInvalidIndex, idx.Inspect(), idx.Type())
+	case left.Type() == object.HASH:
+		return evalHashIndexExpression(left, idx)
+	case left.Type() == object.MODULE && idx.Type() == object.STRING:
+		return evalModuleMemberExpression(left, idx)
+	case left.Type() == object.ERROR && idx.Type() == object.STRING:
+		return evalErrorFieldExpression(left, idx)
 	default:
-		return newError(ErrUnsupportedOperatorIndex, left.Inspect(), left.Type())
+		return newError(object.INDEX_ERROR, ErrUnsupportedOperatorIndex, left.Inspect(), left.Type())
 	}
 }
 
@@ -191,33 +586,163 @@ func evalArrayIndexExpression(left, idx object.Object) object.Object {
 	return arr.Elems[i]
 }
 
-func evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
+// evalStringIndexExpression returns the rune at an index as a string.
+func evalStringIndexExpression(left, idx object.Object) object.Object {
+	runes := []rune(left.(*object.String).Value)
+	i := idx.(*object.Integer).Value
+
+	if i < 0 {
+		i = int64(len(runes)) + i
+	}
+	if i < 0 || i > int64(len(runes)-1) {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[i])}
+}
+
+// evalSliceExpression slices left, the evaluated n.Left.
+func (e *evaluator) evalSliceExpression(n *ast.SliceExpression, left object.Object, env *object.Environment) object.Object {
+	var bounds [3]*int64
+	for i, part := range []ast.Expression{n.Start, n.End, n.Step} {
+		if part == nil {
+			continue
+		}
+		val := e.eval(part, env)
+		if isUnwinding(val) {
+			return val
+		}
+		switch val := val.(type) {
+		case *object.Integer:
+			bounds[i] = &val.Value
+		case *object.Null:
+		default:
+			return newError(object.INDEX_ERROR, ErrInvalidSliceBound, val.Inspect(), val.Type())
+		}
+	}
+
+	step := int64(1)
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return newError(object.ARGUMENT_ERROR, ErrZeroSliceStep)
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idxs := sliceIndices(len(left.Elems), bounds[0], bounds[1], step)
+		elems := make([]object.Object, len(idxs))
+		for i, idx := range idxs {
+			elems[i] = left.Elems[idx]
+		}
+		return &object.Array{Elems: elems}
+	case *object.String:
+		runes := []rune(left.Value)
+		idxs := sliceIndices(len(runes), bounds[0], bounds[1], step)
+		res := make([]rune, len(idxs))
+		for i, idx := range idxs {
+			res[i] = runes[idx]
+		}
+		return &object.String{Value: string(res)}
+	default:
+		return newError(object.INDEX_ERROR, ErrUnsupportedOperatorSlice, left.Inspect(), left.Type())
+	}
+}
+
+// sliceIndices lists the indices selected by a slice of a sequence of length
+// size, following Python: negative bounds count from the end, out of range
+// bounds are clamped and missing bounds cover the whole sequence in the
+// direction of step.
+func sliceIndices(size int, start, end *int64, step int64) []int {
+	n := int64(size)
+	bound := func(b *int64, def, lo, hi int64) int64 {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += n
+		}
+		return min(max(i, lo), hi)
+	}
+
+	idxs := []int{}
+	if step > 0 {
+		from, to := bound(start, 0, 0, n), bound(end, n, 0, n)
+		for i := from; i < to; i += step {
+			idxs = append(idxs, int(i))
+		}
+	} else {
+		from, to := bound(start, n-1, -1, n-1), bound(end, -1, -1, n-1)
+		for i := from; i > to; i += step {
+			idxs = append(idxs, int(i))
+		}
+	}
+	return idxs
+}
+
+func evalHashIndexExpression(left, idx object.Object) object.Object {
+	hash := left.(*object.Hash)
+
+	key, isHashable := idx.(object.Hashable)
+	if !isHashable {
+		return newError(object.INDEX_ERROR, ErrUnhashableKey, idx.Inspect(), idx.Type())
+	}
+
+	val, exists := hash.Get(key)
+	if !exists {
+		return NULL
+	}
+
+	return val
+}
+
+func evalErrorFieldExpression(left, idx object.Object) object.Object {
+	err := left.(*object.Error)
+
+	switch idx.(*object.String).Value {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "kind":
+		return &object.String{Value: string(err.Kind)}
+	case "cause":
+		if err.Cause == nil {
+			return NULL
+		}
+		return err.Cause
+	default:
+		return NULL
+	}
+}
+
+func (e *evaluator) evalIdentifier(id *ast.Identifier, env *object.Environment) object.Object {
 	val, exists := env.Get(id.Value)
 	if exists {
 		return val
 	}
 
-	builtin, exists := builtins[id.Value]
+	builtin, exists := e.opts.Builtins.Lookup(id.Value)
 	if exists {
 		return builtin
 	}
 
-	return newError(ErrIdentifierNotFound, id.Value)
+	return newError(object.NAME_ERROR, ErrIdentifierNotFound, id.Value)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	cond := Eval(ie.Condition, env)
+func (e *evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	cond := e.eval(ie.Condition, env)
 
-	if isError(cond) {
+	if isUnwinding(cond) {
 		return cond
 	}
 
 	if isTruthy(cond) {
-		return Eval(ie.Body, env)
+		return e.eval(ie.Body, env)
 	} else if ie.Else != nil {
 		switch el := ie.Else.(type) {
 		case *ast.BlockStatement, *ast.IfExpression:
-			return Eval(el, env)
+			return e.eval(el, env)
 		default:
 			return NULL
 		}
@@ -226,6 +751,50 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	return NULL
 }
 
+func (e *evaluator) evalTryExpression(te *ast.TryExpression, env *object.Environment) object.Object {
+	res := e.eval(te.Body, env)
+
+	if exc, isExc := res.(*object.Exception); isExc && te.Catch != nil {
+		if !exc.Err.Kind.Catchable() {
+			return exc
+		}
+		catchEnv := object.NewLocalEnvironment(env)
+		catchEnv.Set(te.CatchParam.Value, exc.Err)
+		res = e.eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		if exc, isExc := res.(*object.Exception); isExc && !exc.Err.Kind.Catchable() {
+			return exc
+		}
+
+		// A finally block that throws or returns replaces the outcome of the
+		// rest of the try expression
+		fin := e.eval(te.Finally, env)
+		if isUnwinding(fin) {
+			return fin
+		}
+	}
+
+	return res
+}
+
+// throw starts unwinding the stack with val. Values other than errors are
+// wrapped in an error of kind USER_ERROR.
+func throw(val object.Object) *object.Exception {
+	switch val := val.(type) {
+	case *object.Error:
+		// Each throw gets its own traceback, leaving val untouched
+		err := *val
+		err.Frames = nil
+		return &object.Exception{Err: &err}
+	case *object.String:
+		return newError(object.USER_ERROR, "%s", val.Value)
+	default:
+		return newError(object.USER_ERROR, "%s", val.Inspect())
+	}
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL, FALSE:
@@ -245,13 +814,15 @@ func evalInfixExpression(op string, left, right object.Object) object.Object {
 		return evalStringInfixExpression(op, left, right)
 	// The following cases are only for boolean expressions
 	case op == "==":
-		return nativeBoolToObjBool(left == right)
+		return nativeBoolToObjBool(objectsEqual(left, right))
 	case op == "!=":
-		return nativeBoolToObjBool(left != right)
+		return nativeBoolToObjBool(!objectsEqual(left, right))
+	case left.Type() == object.ARRAY && right.Type() == object.ARRAY && isOrdering(op):
+		return evalOrderingExpression(op, left, right)
 	case left.Type() != right.Type():
-		return newError(ErrTypeMismatch, left.Type(), op, right.Type())
+		return newError(object.TYPE_ERROR, ErrTypeMismatch, left.Type(), op, right.Type())
 	default:
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+		return newError(object.TYPE_ERROR, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
 	}
 }
 
@@ -264,6 +835,9 @@ func evalIntegerInfixExpression(op string, left, right object.Object) object.Obj
 	case "*":
 		return &object.Integer{Value: leftInt.Value * rightInt.Value}
 	case "/":
+		if rightInt.Value == 0 {
+			return newError(object.ZERO_DIVISION_ERROR, ErrDivisionByZero, leftInt.Value)
+		}
 		return &object.Integer{Value: leftInt.Value / rightInt.Value}
 	case "+":
 		return &object.Integer{Value: leftInt.Value + rightInt.Value}
@@ -283,19 +857,26 @@ func evalIntegerInfixExpression(op string, left, right object.Object) object.Obj
 	case "!=":
 		return nativeBoolToObjBool(leftInt.Value != rightInt.Value)
 	default:
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+		return newError(object.TYPE_ERROR, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
 	}
 }
 
 func evalStringInfixExpression(op string, left, right object.Object) object.Object {
-	if op != "+" {
-		return newError(ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
-	}
-
 	leftVal := left.(*object.String).Value
 	rightVal := right.(*object.String).Value
 
-	return &object.String{Value: leftVal + rightVal}
+	switch {
+	case op == "+":
+		return &object.String{Value: leftVal + rightVal}
+	case op == "==":
+		return nativeBoolToObjBool(leftVal == rightVal)
+	case op == "!=":
+		return nativeBoolToObjBool(leftVal != rightVal)
+	case isOrdering(op):
+		return evalOrderingExpression(op, left, right)
+	default:
+		return newError(object.TYPE_ERROR, ErrUnsupportedOperatorInfix, left.Type(), op, right.Type())
+	}
 }
 
 func evalPrefixExpression(op string, right object.Object) object.Object {
@@ -305,7 +886,20 @@ func evalPrefixExpression(op string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError(ErrUnsupportedOperatorPrefix, op, right.Type())
+		return newError(object.TYPE_ERROR, ErrUnsupportedOperatorPrefix, op, right.Type())
+	}
+}
+
+func evalPostfixExpression(op string, left object.Object) object.Object {
+	switch op {
+	case "?":
+		// An error value returns early from the enclosing function
+		if left.Type() == object.ERROR {
+			return &object.ReturnValue{Value: left}
+		}
+		return left
+	default:
+		return newError(object.TYPE_ERROR, ErrUnsupportedOperatorPostfix, left.Type(), op)
 	}
 }
 
@@ -325,18 +919,21 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	if intObj, isInt := right.(*object.Integer); isInt {
-		intObj.Value = -intObj.Value
-		return intObj
+		return &object.Integer{Value: -intObj.Value}
 	}
-	return newError(ErrUnsupportedOperatorPrefix, "-", right.Type())
+	return newError(object.TYPE_ERROR, ErrUnsupportedOperatorPrefix, "-", right.Type())
 }
 
-func evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+func (e *evaluator) evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+	if exc := e.tick(); exc != nil {
+		return exc.Err
+	}
+
 	for _, s := range stmts {
-		res = Eval(s, env)
+		res = e.eval(s, env)
 
-		if err, isErr := res.(*object.Error); isErr {
-			return err
+		if exc, isExc := res.(*object.Exception); isExc {
+			return exc.Err
 		}
 		if rv, isRetVal := res.(*object.ReturnValue); isRetVal {
 			return rv.Value
@@ -345,12 +942,16 @@ func evalProgram(stmts []ast.Statement, env *object.Environment) (res object.Obj
 	return res
 }
 
-func evalBlockStatements(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+func (e *evaluator) evalBlockStatements(stmts []ast.Statement, env *object.Environment) (res object.Object) {
+	if err := e.tick(); err != nil {
+		return err
+	}
+
 	for _, s := range stmts {
-		res = Eval(s, env)
+		res = e.eval(s, env)
 
-		if err, isErr := res.(*object.Error); isErr {
-			return err
+		if exc, isExc := res.(*object.Exception); isExc {
+			return exc
 		}
 
 		if rv, isRetVal := res.(*object.ReturnValue); isRetVal {
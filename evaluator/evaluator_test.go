@@ -1,11 +1,20 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nayyara-airlangga/basedlang/lexer"
 	"github.com/nayyara-airlangga/basedlang/object"
 	"github.com/nayyara-airlangga/basedlang/parser"
+	"github.com/nayyara-airlangga/basedlang/vfs"
 )
 
 func TestErrorHandling(t *testing.T) {
@@ -242,7 +251,7 @@ func TestFunctionApplication(t *testing.T) {
 		{"let double = fn(x) { x * 2; }; double(5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
-		{"let add = fn(x, y) { x + y; }; add(5);", "wrong number of arguments. got=1, want=2"},
+		{"let add = fn(x, y) { x + y; }; add(5);", "wrong number of arguments to add. got=1, want=2"},
 		{"fn(x) { x * 3; }(5)", 15},
 		{`
 		let muller = fn(x) { fn(y) { x * y } };
@@ -485,6 +494,444 @@ func TestBangOperator(t *testing.T) {
 	}
 }
 
+func TestEvalContextLimits(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), -time.Second)
+	defer cancelExpired()
+
+	tests := []struct {
+		ctx      context.Context
+		input    string
+		opts     Options
+		kind     object.ErrorKind
+		expected string
+	}{
+		{
+			canceled,
+			"1 + 1",
+			Options{},
+			object.CANCELED_ERROR,
+			"evaluation canceled",
+		},
+		{
+			expired,
+			"let f = fn() { f() }; f()",
+			Options{},
+			object.TIMEOUT_ERROR,
+			"evaluation timed out",
+		},
+		{
+			context.Background(),
+			"let f = fn() { f() }; f()",
+			Options{MaxSteps: 100},
+			object.BUDGET_ERROR,
+			"step budget exhausted: limit=100",
+		},
+		{
+			context.Background(),
+			"let f = fn(s) { f(s + s) }; f(\"ab\")",
+			Options{MaxAllocs: 1000},
+			object.BUDGET_ERROR,
+			"allocation budget exhausted: limit=1000",
+		},
+		{
+			context.Background(),
+			`repeat("ab", 100000000)`,
+			Options{MaxAllocs: 1000},
+			object.BUDGET_ERROR,
+			"allocation budget exhausted: limit=1000",
+		},
+		{
+			context.Background(),
+			`let s = repeat("a", 900); pad_left(s, 1000)`,
+			Options{MaxAllocs: 1000},
+			object.BUDGET_ERROR,
+			"allocation budget exhausted: limit=1000",
+		},
+		{
+			context.Background(),
+			"let f = fn() { f() }; f()",
+			Options{MaxDepth: 50},
+			object.STACK_OVERFLOW_ERROR,
+			"maximum call depth exceeded: limit=50",
+		},
+		{
+			context.Background(),
+			"let f = fn() { f() }; f()",
+			Options{},
+			object.STACK_OVERFLOW_ERROR,
+			"maximum call depth exceeded: limit=10000",
+		},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := EvalContext(tc.ctx, program, object.NewEnvironment(), tc.opts)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tc.kind {
+			t.Errorf("wrong error kind. expected=%q, got=%q", tc.kind, errObj.Kind)
+		}
+		if errObj.Message != tc.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tc.expected, errObj.Message)
+		}
+	}
+}
+
+func TestEvalContextWithinLimits(t *testing.T) {
+	input := `
+	let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } };
+	sum(100)
+	`
+	program := parser.New(lexer.New(input)).Parse()
+	opts := Options{MaxSteps: 1000, MaxAllocs: 1000, MaxDepth: 200}
+	evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+	testIntegerObject(t, evaluated, 5050)
+}
+
+func TestErrorStackTraces(t *testing.T) {
+	input := `let inner = fn(a) { a + x };
+let outer = fn(b) {
+  inner(b * 2)
+};
+outer(1)`
+
+	evaluated := testEval(input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expectedFrames := []object.Frame{
+		{Function: "inner", Args: "2", Line: 3, Column: 8},
+		{Function: "outer", Args: "1", Line: 5, Column: 6},
+	}
+	if len(errObj.Frames) != len(expectedFrames) {
+		t.Fatalf("wrong number of frames. expected=%d, got=%d (%+v)",
+			len(expectedFrames), len(errObj.Frames), errObj.Frames)
+	}
+	for i, expected := range expectedFrames {
+		if errObj.Frames[i] != expected {
+			t.Errorf("frames[%d] wrong. expected=%+v, got=%+v", i, expected, errObj.Frames[i])
+		}
+	}
+
+	expectedInspect := `ERROR: NameError: identifier not found: x
+Traceback (most recent call last):
+  outer(1) at line 5, column 6
+  inner(2) at line 3, column 8`
+	if errObj.Inspect() != expectedInspect {
+		t.Errorf("wrong Inspect output. expected=%q, got=%q", expectedInspect, errObj.Inspect())
+	}
+}
+
+func TestRethrownErrorTraceback(t *testing.T) {
+	input := `let e = error("X", "y");
+let fail = fn() { throw e };
+try { fail() } catch (err) { 0 };
+try { fail() } catch (err) { 0 };
+fail()`
+
+	env := object.NewEnvironment()
+	evaluated := Eval(parser.New(lexer.New(input)).Parse(), env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expectedFrames := []object.Frame{{Function: "fail", Args: "", Line: 5, Column: 5}}
+	if len(errObj.Frames) != len(expectedFrames) || errObj.Frames[0] != expectedFrames[0] {
+		t.Errorf("wrong frames. expected=%+v, got=%+v", expectedFrames, errObj.Frames)
+	}
+
+	thrown, _ := env.Get("e")
+	if frames := thrown.(*object.Error).Frames; len(frames) != 0 {
+		t.Errorf("throwing e added frames to it. got=%+v", frames)
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 + true } catch (e) { 2 }", 2},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { [1][true] } catch (e) { e["kind"] }`, "IndexError"},
+		{`try { foo } catch (e) { e["kind"] }`, "NameError"},
+		{`try { 1 / 0 } catch (e) { e["kind"] + ": " + e["message"] }`, "ZeroDivisionError: division by zero: 1 / 0"},
+		{`let x = 0; try { 10 / x } catch (e) { -1 }`, -1},
+		{`1 / 0`, "division by zero: 1 / 0"},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw 42 } catch (e) { e["kind"] }`, "Error"},
+		{`try { try { 1 + true } catch (e) { throw e } } catch (e) { e["kind"] }`, "TypeError"},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() }; try { g() } catch (e) { e["message"] }`, "deep"},
+		{`let e = 1; try { throw "x" } catch (e) { 2 }; e`, 1},
+		{"let f = fn() { try { return 1 } finally { 5 } }; f()", 1},
+		{"let f = fn() { try { return 1 } finally { return 2 } }; f()", 2},
+		{"let f = fn() { try { 1 + true } finally { return 3 } }; f()", 3},
+		{"let f = fn() { try { 1 + true } catch (e) { return 4 } finally { 5 } }; f()", 4},
+		{`try { 1 + true } finally { 5 }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { 1 } finally { throw "from finally" }`, "from finally"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestUncatchableErrors(t *testing.T) {
+	input := "let f = fn() { f() }; try { f() } catch (e) { 1 } finally { 2 }"
+	program := parser.New(lexer.New(input)).Parse()
+	evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), Options{MaxSteps: 50})
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.BUDGET_ERROR {
+		t.Errorf("wrong error kind. expected=%q, got=%q", object.BUDGET_ERROR, errObj.Kind)
+	}
+}
+
+func TestErrorValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`is_error(error("ValueError", "bad input"))`, true},
+		{`is_error(1)`, false},
+		{`let e = error("ValueError", "bad input"); e["kind"]`, "ValueError"},
+		{`let e = error("ValueError", "bad input"); e["message"]`, "bad input"},
+		{`let e = error("ValueError", "bad input"); 5`, 5},
+		{`let e = error("IOError", "outer", error("ValueError", "inner")); e["cause"]["message"]`, "inner"},
+		{`error("ValueError", "bad input")["cause"]`, nil},
+		{`
+		let parse = fn(x) { if (x < 0) { error("ValueError", "negative") } else { x } };
+		let double = fn(x) { parse(x)? * 2 };
+		double(4)
+		`, 8},
+		{`
+		let parse = fn(x) { if (x < 0) { error("ValueError", "negative") } else { x } };
+		let double = fn(x) { parse(x)? * 2 };
+		is_error(double(-1))
+		`, true},
+		{`try { throw error("ValueError", "thrown") } catch (e) { e["kind"] }`, "ValueError"},
+		{`error("ValueError")`, "wrong number of arguments. got=1, want=2 or 3"},
+		{`error(1, "x")`, "invalid argument: kind for error must be a STRING. got=1 (INTEGER)"},
+		{`error("ValueError", "x", 1)`, "invalid argument: cause for error must be a ERROR. got=1 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestErrorCauseInspect(t *testing.T) {
+	evaluated := testEval(`error("IOError", "read failed", error("ValueError", "bad byte"))`)
+	expected := "ERROR: IOError: read failed\nCaused by: ValueError: bad byte"
+	if evaluated.Inspect() != expected {
+		t.Errorf("wrong Inspect output. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestBuiltinRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("math.abs", func(c object.CallContext, args ...object.Object) object.Object {
+		n := args[0].(*object.Integer).Value
+		if n < 0 {
+			n = -n
+		}
+		return &object.Integer{Value: n}
+	})
+	registry.Register("twice", func(c object.CallContext, args ...object.Object) object.Object {
+		res := c.Call(args[0], args[1])
+		if res.Type() == object.EXCEPTION {
+			return res
+		}
+		return c.Call(args[0], res)
+	})
+	registry.Register("lookup", func(c object.CallContext, args ...object.Object) object.Object {
+		val, exists := c.Env().Get(args[0].(*object.String).Value)
+		if !exists {
+			return NULL
+		}
+		return val
+	})
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`math["abs"](-3)`, 3},
+		{`twice(fn(x) { x * 3 }, 2)`, 18},
+		{`let f = fn() { let local = 7; lookup("local") }; f()`, 7},
+		{`try { twice(fn(x) { x + true }, 1) } catch (e) { e["kind"] }`, "TypeError"},
+		{`len("abc")`, "identifier not found: len"},
+		{`let twice = fn(f, x) { 0 }; twice(fn(x) { x }, 1)`, 0},
+		{`math.abs = 5`, "cannot modify frozen {abs: builtin function} (HASH)"},
+		{`math["abs"] = 5`, "cannot modify frozen {abs: builtin function} (HASH)"},
+	}
+
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), Options{Builtins: registry})
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("wrong string. expected=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+
+	if _, exists := StdRegistry().Lookup("twice"); exists {
+		t.Errorf("builtin registered in one registry leaked into another")
+	}
+
+	testEval(`try { fs.read_file = 5 } catch (e) { 0 }`)
+	if got := testEval(`fs.read_file`); got.Type() != object.BUILTIN {
+		t.Errorf("a script replaced a builtin of the default registry. got=%s", got.Inspect())
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, []int{2, 4, 6}},
+		{`map([], fn(x) { x * 2 })`, []int{}},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x }, 10)`, 20},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc * x })`, 24},
+		{`reduce([], fn(acc, x) { acc + x })`, "invalid argument: reduce of empty array with no initial value"},
+		{`each([1, 2], fn(x) { x })`, nil},
+		{`find([1, 2, 3], fn(x) { x > 1 })`, 2},
+		{`find([1, 2, 3], fn(x) { x > 5 })`, nil},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, true},
+		{`any([], fn(x) { true })`, false},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, true},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, false},
+		{`sort([3, 1, 2])`, []int{1, 2, 3}},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, []int{3, 2, 1}},
+		{`sort([3, 1, 2], fn(a, b) { a < b })`, []int{1, 2, 3}},
+		{`map(sort([[2, 1], [1, 2], [1, 1]], fn(a, b) { a[0] - b[0] }), fn(p) { p[1] })`, []int{2, 1, 1}},
+		{`sort([1, "a"])`, "invalid argument: a (STRING) and 1 (INTEGER) can't be compared"},
+		{`sort([1, 2], fn(a, b) { "x" })`, "invalid result: comparator for sort must return an integer or a boolean. got=x (STRING)"},
+		{`map(zip([1, 2, 3], [10, 20]), fn(p) { p[0] + p[1] })`, []int{11, 22}},
+		{`flat_map([1, 2], fn(x) { [x, x * 10] })`, []int{1, 10, 2, 20}},
+		{`map([1, 2], fn(x) { x + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`sort([2, 1], fn(a, b) { throw "cmp failed" })`, "cmp failed"},
+		{`try { each([1], fn(x) { throw "inside" }) } catch (e) { len(e["message"]) }`, 6},
+		{`map(1, fn(x) { x })`, "invalid argument: first argument for map must be an array. got=1 (INTEGER)"},
+		{`filter([1], 2)`, "invalid argument: second argument for filter must be a function. got=2 (INTEGER)"},
+		{`map([1])`, "wrong number of arguments. got=1, want=2"},
+		{`map([-1, -2], len)`, "invalid argument: -1 (INTEGER) not supported for len"},
+		{`len(map([1, 2, 3], fn(x) { x }))`, 3},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			arr, isArr := evaluated.(*object.Array)
+			if !isArr {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elems) != len(expected) {
+				t.Errorf("incorrect number of elements. expected=%d, got=%d", len(expected), len(arr.Elems))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, arr.Elems[i], int64(expectedElem))
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestCollectionBuiltinsOnLargeArrays(t *testing.T) {
+	env := object.NewEnvironment()
+	elems := make([]object.Object, 100000)
+	for i := range elems {
+		elems[i] = &object.Integer{Value: int64(i)}
+	}
+	env.Set("xs", &object.Array{Elems: elems})
+
+	input := `reduce(map(filter(xs, fn(x) { x > 0 }), fn(x) { 1 }), fn(acc, x) { acc + x }, 0)`
+	evaluated := Eval(parser.New(lexer.New(input)).Parse(), env)
+	testIntegerObject(t, evaluated, 99999)
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("obj is not NULL. got=%T (%+v)", obj, obj)
@@ -526,3 +973,881 @@ func testEval(input string) object.Object {
 
 	return Eval(program, env)
 }
+
+// testResult compares what input evaluated to against expected, using the
+// Error() text for errors and Inspect() for everything else.
+func testResult(t *testing.T, input string, evaluated object.Object, expected string) bool {
+	got := evaluated.Inspect()
+	if errObj, isErr := evaluated.(*object.Error); isErr {
+		got = errObj.Error()
+	}
+	if got != expected {
+		t.Errorf("wrong result for %s. expected=%q, got=%q", input, expected, got)
+		return false
+	}
+	return true
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`len("héllo")`, 5},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[-1]`, "o"},
+		{`"héllo"[5]`, nil},
+		{`"abc"["x"]`, errorMessage("invalid argument: index x (STRING) is not an integer")},
+		{`join(split("a,b,,c", ","), "|")`, "a|b||c"},
+		{`join(split("añb", ""), "-")`, "a-ñ-b"},
+		{`join([1, "a", true])`, "1atrue"},
+		{`trim("  hi  ")`, "hi"},
+		{`trim_left("  hi  ")`, "hi  "},
+		{`trim_right("  hi  ")`, "  hi"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("ÀB")`, "àb"},
+		{`contains("hello", "ell")`, true},
+		{`starts_with("hello", "he")`, true},
+		{`ends_with("hello", "he")`, false},
+		{`index_of("héllo", "l")`, 2},
+		{`index_of("hello", "z")`, -1},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, errorMessage("invalid argument: count for repeat must not be negative. got=-1")},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", -2)`, "lo"},
+		{`substr("héllo", 3, 10)`, "lo"},
+		{`substr("héllo", 10)`, ""},
+		{`substr("héllo", 1, 9223372036854775807)`, "éllo"},
+		{`substr("héllo", -9223372036854775807, 2)`, "hé"},
+		{`pad_left("7", 3, "0")`, "007"},
+		{`pad_right("ab", 5, "xy")`, "abxyx"},
+		{`pad_left("héllo", 3)`, "héllo"},
+		{`pad_left("a", 3, "")`, errorMessage("invalid argument: padding for pad_left must not be empty")},
+		{`len(chars("héllo"))`, 5},
+		{`format("%s is %d", "x", 42)`, "x is 42"},
+		{`format("%5s|%-3d|%t|%v", "ab", 7, true, [1, 2])`, "   ab|7  |true|[1, 2]"},
+		{`format("100%%")`, "100%"},
+		{`format("%[2]s %[1]s", "a", "b")`, "b a"},
+		{`format("%*d|%x|%s", 3, 7, "hi", [1])`, "  7|6869|[1]"},
+		{`format("%d", "x")`, errorMessage("invalid argument: verb %d for format can't print x (STRING)")},
+		{`format("%5t", 1)`, errorMessage("invalid argument: verb %5t for format can't print 1 (INTEGER)")},
+		{`format("%*d", "w", 1)`, errorMessage("invalid argument: verb %* for format can't print w (STRING)")},
+		{`format("%s and %s", "a")`, errorMessage("invalid argument: verb %s for format has no argument")},
+		{`format("%s", "a", "b")`, errorMessage("invalid argument: format uses 1 of its 2 arguments")},
+		{`format("%z", 1)`, errorMessage("invalid argument: unknown verb %z for format")},
+		{`format("%[x]d", 1)`, errorMessage("invalid argument: unknown verb %[ for format")},
+		{`format("100%")`, errorMessage("invalid argument: format ends in the middle of verb %")},
+		{`len(repeat("", 4611686018427387904))`, 0},
+		{`repeat("ab", 4611686018427387904)`, errorMessage("invalid argument: result of repeat would be too large")},
+		{`repeat("ab", 1073741824)`, errorMessage("invalid argument: result of repeat would be too large")},
+		{`pad_left("a", 4611686018427387904)`, errorMessage("invalid argument: result of pad_left would be too large")},
+		{`pad_right("a", 9223372036854775807, "xy")`, errorMessage("invalid argument: result of pad_right would be too large")},
+		{`pad_left("a", 4, "héj")`, "héja"},
+		{`upper(1)`, errorMessage("invalid argument: first argument for upper must be a string. got=1 (INTEGER)")},
+		{`contains("a")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %s. got=%T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, expected, str.Value)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+// errorMessage marks an expected error message in tables that also expect
+// string results.
+type errorMessage string
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`[1, 2, 3, 4, 5][1:3]`, []int{2, 3}},
+		{`[1, 2, 3, 4, 5][1:]`, []int{2, 3, 4, 5}},
+		{`[1, 2, 3, 4, 5][:-1]`, []int{1, 2, 3, 4}},
+		{`[1, 2, 3, 4, 5][::2]`, []int{1, 3, 5}},
+		{`[1, 2, 3, 4, 5][::-1]`, []int{5, 4, 3, 2, 1}},
+		{`[1, 2, 3, 4, 5][3:0:-1]`, []int{4, 3, 2}},
+		{`[1, 2, 3, 4, 5][-2:]`, []int{4, 5}},
+		{`[1, 2, 3, 4, 5][-100:100]`, []int{1, 2, 3, 4, 5}},
+		{`[1, 2, 3, 4, 5][4:1]`, []int{}},
+		{`[][:]`, []int{}},
+		{`let n = 2; [1, 2, 3, 4][n - 1:n + 1]`, []int{2, 3}},
+		{`let none = if (false) { 0 }; [1, 2, 3][none:2]`, []int{1, 2}},
+		{`"héllo"[1:3]`, "él"},
+		{`"héllo"[::-1]`, "olléh"},
+		{`"héllo"[10:]`, ""},
+		{`[1, 2][::0]`, errorMessage("invalid argument: slice step cannot be zero")},
+		{`[1, 2]["a":]`, errorMessage("invalid argument: slice bound a (STRING) is not an integer")},
+		{`5[1:]`, errorMessage("unsupported operator: slice not supported on 5 (INTEGER)")},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		switch expected := tc.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %s. got=%T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%q", tc.input, expected, str.Value)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case []int:
+			arr, isArr := evaluated.(*object.Array)
+			if !isArr {
+				t.Errorf("obj not Array for %s. got=%T (%+v)", tc.input, evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elems) != len(expected) {
+				t.Errorf("incorrect number of elements for %s. expected=%d, got=%d", tc.input, len(expected), len(arr.Elems))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, arr.Elems[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestStructuralComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{`[1, 2] == [1, 2]`, true},
+		{`[1, [2, "a"]] == [1, [2, "a"]]`, true},
+		{`[1, 2] == [1, 2, 3]`, false},
+		{`[1, 2] != [2, 1]`, true},
+		{`[1] == ["1"]`, false},
+		{`"a" == "a"`, true},
+		{`"a" != "b"`, true},
+		{`"a" < "b"`, true},
+		{`"b" <= "a"`, false},
+		{`"abc" > "abd"`, false},
+		{`"é" > "z"`, true},
+		{`[1, 2] < [1, 3]`, true},
+		{`[1, 2] < [1, 2, 0]`, true},
+		{`[2] >= [1, 9]`, true},
+		{`[["a"], 1] < [["b"], 0]`, true},
+		{`user == same`, true},
+		{`user == other`, false},
+		{`[user] == [same]`, true},
+		{`let f = fn() { 1 }; f == f`, true},
+		{`fn() { 1 } == fn() { 1 }`, false},
+		{`join(sort(["b", "c", "a"]))`, "abc"},
+		{`sort([[2, 1], [1, 2], [1, 1]])[0][1]`, 1},
+		{`[1] < ["a"]`, errorMessage("invalid argument: 1 (INTEGER) and a (STRING) can't be compared")},
+		{`"a" - "b"`, errorMessage("unsupported operator: STRING - STRING")},
+		{`user < same`, errorMessage("unsupported operator: HASH < HASH")},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		for name, v := range map[string]any{
+			"user":  map[string]any{"name": "ann", "tags": []string{"x"}},
+			"same":  map[string]any{"tags": []string{"x"}, "name": "ann"},
+			"other": map[string]any{"name": "ann", "tags": []string{"y"}},
+		} {
+			obj, err := ToObject(v)
+			if err != nil {
+				t.Fatalf("ToObject returned error: %v", err)
+			}
+			env.Set(name, obj)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			if !testBooleanObject(t, evaluated, expected) {
+				t.Errorf("wrong result for %s", tc.input)
+			}
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("wrong result for %s. expected=%q, got=%+v", tc.input, expected, evaluated)
+			}
+		case errorMessage:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != string(expected) {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestIOBuiltins(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdin  string
+		stdout string
+		stderr string
+	}{
+		{`puts("a", 1, [true])`, "", "a\n1\n[true]\n", ""},
+		{`puts()`, "", "", ""},
+		{`print("a", 1); print("b")`, "", "a 1b", ""},
+		{`printf("%s=%03d", "x", 7)`, "", "x=007", ""},
+		{`eprint("oops", 2)`, "", "", "oops 2"},
+		{`puts(read_line()); puts(read_line())`, "one\r\ntwo", "one\ntwo\n", ""},
+		{`read_line(); puts(read_line())`, "only\n", "null\n", ""},
+		{`let first = read_line(); print(read_all(), first)`, "a\nb\nc\n", "b\nc\n a", ""},
+	}
+
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		opts := Options{Stdout: &stdout, Stderr: &stderr, Stdin: strings.NewReader(tc.stdin)}
+		program := parser.New(lexer.New(tc.input)).Parse()
+
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+		if errObj, isErr := evaluated.(*object.Error); isErr {
+			t.Errorf("unexpected error for %s: %s", tc.input, errObj.Inspect())
+			continue
+		}
+		if stdout.String() != tc.stdout {
+			t.Errorf("wrong output for %s. expected=%q, got=%q", tc.input, tc.stdout, stdout.String())
+		}
+		if stderr.String() != tc.stderr {
+			t.Errorf("wrong error output for %s. expected=%q, got=%q", tc.input, tc.stderr, stderr.String())
+		}
+	}
+}
+
+func TestFSBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fs["write_file"]("/notes.txt", "hello"); fs["read_file"]("notes.txt")`, "hello"},
+		{`fs["mkdir"]("logs/old"); fs["write_file"]("logs/a.log", ""); join(fs["list_dir"]("logs"), ",")`, "a.log,old"},
+		{`fs["exists"]("notes.txt")`, "true"},
+		{`fs.exists("notes.txt")`, "true"},
+		{`fs["exists"]("missing.txt")`, "false"},
+		{`fs["write_file"]("tmp.txt", "x"); fs["remove"]("tmp.txt"); fs["exists"]("tmp.txt")`, "false"},
+		{`fs["read_file"]("missing.txt")`, "HostError: open missing.txt: file does not exist"},
+		{`fs["read_file"]("../etc/passwd")`, "PermissionError: read ../etc/passwd: path escapes the allowed roots"},
+		{`fs["exists"]("logs/../../x")`, "PermissionError: stat logs/../../x: path escapes the allowed roots"},
+		{`try { fs["read_file"]("..") } catch (e) { e["kind"] }`, "PermissionError"},
+		{`fs["write_file"]("x.txt", 1)`, "TypeError: invalid argument: second argument for fs.write_file must be a string. got=1 (INTEGER)"},
+	}
+
+	fsys := vfs.NewMemory()
+	for _, tc := range tests {
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), Options{FS: fsys})
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+
+	evaluated := testEval(`fs["read_file"]("notes.txt")`)
+	errObj, isErr := evaluated.(*object.Error)
+	if !isErr || errObj.Kind != object.PERMISSION_ERROR || errObj.Message != ErrNoFilesystem {
+		t.Errorf("fs builtins should fail without a filesystem. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestJSONBuiltins(t *testing.T) {
+	tests := []struct {
+		src      string
+		input    string
+		expected string
+	}{
+		{`{"b": [1, true, null, "x"], "a": {}}`, `json_encode(json_decode(src))`, `{"b":[1,true,null,"x"],"a":{}}`},
+		{`{"n": -42, "s": "é\n<"}`, `json_encode(json_decode(src))`, `{"n":-42,"s":"é\n<"}`},
+		{`{"user": {"name": "ann"}}`, `json_decode(src)["user"]["name"]`, `ann`},
+		{`[1, {"a": [2]}]`, `json_encode(json_decode(src), 2)`, "[\n  1,\n  {\n    \"a\": [\n      2\n    ]\n  }\n]"},
+		{`[1]`, `json_encode(json_decode(src), "    ")`, "[\n    1\n]"},
+		{`"x"`, `json_decode(src) == "x"`, `true`},
+		{``, `json_encode([fn(x) { x }])`, "TypeError: invalid argument: FUNCTION can't be encoded as JSON"},
+		{``, `json_encode(len)`, "TypeError: invalid argument: BUILTIN can't be encoded as JSON"},
+		{``, `json_encode(cyclic)`, "ArgumentError: invalid argument: cyclic structure can't be encoded as JSON"},
+		{``, `json_encode([shared, shared])`, `[{"k":true},{"k":true}]`},
+		{``, `json_encode(keyed)`, `{"1":"one","true":"yes"}`},
+		{"[1,\n  2,,]", `json_decode(src)`, "ArgumentError: invalid JSON at line 2, column 5: invalid character ',' looking for beginning of value"},
+		{`{"a": 1.5}`, `json_decode(src)`, "ArgumentError: invalid JSON at line 1, column 7: number 1.5 is not an integer"},
+		{`[1] [2]`, `json_decode(src)`, "ArgumentError: invalid JSON at line 1, column 5: unexpected data after top-level value"},
+		{`{"a": `, `json_decode(src)`, "ArgumentError: invalid JSON at line 1, column 7: unexpected end of JSON input"},
+		{`[1]`, `json_decode(1)`, "TypeError: invalid argument: first argument for json_decode must be a string. got=1 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		env.Set("src", &object.String{Value: tc.src})
+
+		cyclic := object.NewHash()
+		cyclic.Set(&object.String{Value: "self"}, cyclic)
+		env.Set("cyclic", cyclic)
+		shared := object.NewHash()
+		shared.Set(&object.String{Value: "k"}, TRUE)
+		env.Set("shared", shared)
+		keyed := object.NewHash()
+		keyed.Set(&object.Integer{Value: 1}, &object.String{Value: "one"})
+		keyed.Set(TRUE, &object.String{Value: "yes"})
+		env.Set("keyed", keyed)
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		testResult(t, fmt.Sprintf("%s with %q", tc.input, tc.src), evaluated, tc.expected)
+	}
+}
+
+func TestRegexBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`#/^\d+$/`, `#/^\d+$/`},
+		{`regex("a/b")`, `#/a\/b/`},
+		{`regex_match(#/^\d+$/, "123")`, "true"},
+		{`regex_match(#/^\d+$/, "12a")`, "false"},
+		{`regex_match("^[a-z]+$", "abc")`, "true"},
+		{`regex_find_all(#/\d+/, "a1 b22 c333")`, "[1, 22, 333]"},
+		{`regex_find_all(#/\d+/, "a1 b22 c333", 2)`, "[1, 22]"},
+		{`regex_find_all(#/(\w+)=(\d+)?/, "a=1 b=")`, "[[a=1, a, 1], [b=, b, null]]"},
+		{`regex_find_all(#/x/, "abc")`, "[]"},
+		{`regex_replace(#/(\w+)@(\w+)/, "ann@home bob@work", "$2:$1")`, "home:ann work:bob"},
+		{`regex_replace(#/\d+/, "a1 b22", fn(m) { repeat("#", len(m)) })`, "a# b##"},
+		{`regex_replace(#/(\d)(\d)/, "12 34", fn(m) { m[2] + m[1] })`, "21 43"},
+		{`regex_split(#/\s*,\s*/, "a , b,c")`, "[a, b, c]"},
+		{`regex_split(#/,/, "a,b,c", 2)`, "[a, b,c]"},
+		{`let re = #/^(\w+) (\S+)$/; map(["GET /", "bad"], fn(l) { regex_match(re, l) })`, "[true, false]"},
+		{`#/a/ == regex("a")`, "true"},
+		{`let r = 10; r/2`, "5"},
+		{`let r = 10; let x = 2; r/x/1`, "5"},
+		{`regex("(")`, "ArgumentError: invalid argument: ( is not a valid regex: error parsing regexp: missing closing ): `(`"},
+		{`regex_match(1, "a")`, "TypeError: invalid argument: first argument for regex_match must be a regex or a string. got=1 (INTEGER)"},
+		{`regex_replace(#/a/, "a", fn(m) { 1 })`, "TypeError: invalid result: replacement function for regex_replace must return a string. got=1 (INTEGER)"},
+		{`regex_replace(#/a/, "a", 1)`, "TypeError: invalid argument: third argument for regex_replace must be a string or a function. got=1 (INTEGER)"},
+	}
+
+	for _, tc := range tests {
+		testResult(t, tc.input, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestModules(t *testing.T) {
+	files := map[string]string{
+		"/app/main.bl":          `import "lib/strings" as s;`,
+		"/app/lib/strings.bl":   `import "../counter"; export let shout = fn(x) { counter["tick"]; upper(x) + "!" }; let hidden = 1;`,
+		"/app/counter.bl":       `puts("loading counter"); export let tick = 1;`,
+		"/shared/util.bl":       `export let twice = fn(x) { x * 2 };`,
+		"/app/cycle/a.bl":       `import "b";`,
+		"/app/cycle/b.bl":       `import "a";`,
+		"/app/broken.bl":        `let = 1;`,
+		"/app/throws.bl":        `throw "boom";`,
+		"/app/lib/reimport.bl":  `import "../counter"; export let tick = counter["tick"];`,
+		"/app/lib/recursive.bl": `export let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };`,
+		"/app/pair.bl":          `export let [first, ...rest] = [1, 2, 3];`,
+		"/app/math.bl":          `export fn square(x) { x * x }; fn hidden() { 0 }`,
+		"/app/config.bl":        `export const limits = freeze([1, 2]);`,
+	}
+	memFS := vfs.NewMemory()
+	for name, src := range files {
+		memFS.MkdirAll(path.Dir(name))
+		memFS.WriteFile(name, []byte(src))
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+		output   string
+	}{
+		{`import "lib/strings" as s; s["shout"]("hi")`, "HI!", "loading counter\n"},
+		{`import "lib/strings"; strings`, "<module /app/lib/strings.bl>", "loading counter\n"},
+		{`import "lib/strings" as s; import "lib/reimport" as r; r["tick"]`, "1", "loading counter\n"},
+		{`import "util"; util["twice"](21)`, "42", ""},
+		{`import "util"; util.twice(util.twice(1))`, "4", ""},
+		{`import "lib/recursive" as r; r["fact"](5)`, "120", ""},
+		{`import "lib/strings" as s; s["hidden"]`, "NameError: module /app/lib/strings.bl has no export hidden", "loading counter\n"},
+		{`import "missing"`, "ImportError: module not found: missing", ""},
+		{`import "cycle/a"`, "ImportError: import cycle: /app/cycle/a.bl -> /app/cycle/b.bl -> /app/cycle/a.bl", ""},
+		{`import "broken"`, "ImportError: could not parse module /app/broken.bl: expected next token to be IDENT, got = instead; no prefix parse function found for =", ""},
+		{`try { import "throws" } catch (e) { e["message"] }`, "boom", ""},
+		{`export let x = 1; x`, "1", ""},
+		{`import "pair"; pair.first + len(pair.rest)`, "3", ""},
+		{`import "math"; [math.square(3), math.square]`, "[9, <fn square/1>]", ""},
+		{`import "math"; math.hidden`, "NameError: module /app/math.bl has no export hidden", ""},
+		{`import "config"; config.limits[0] = 5`, "TypeError: cannot modify frozen [1, 2] (ARRAY)", ""},
+		{`const config = 1; import "config"`, "NameError: cannot reassign constant config", ""},
+	}
+
+	for _, tc := range tests {
+		var out bytes.Buffer
+		opts := Options{
+			File:   "/app/main.bl",
+			Stdout: &out,
+		}
+		opts.Modules = NewModuleLoader("/shared")
+		opts.Modules.ReadFile = memFS.ReadFile
+
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+		testResult(t, tc.input, evaluated, tc.expected)
+		if out.String() != tc.output {
+			t.Errorf("wrong output for %s. expected=%q, got=%q", tc.input, tc.output, out.String())
+		}
+	}
+}
+
+func TestModuleFilesystem(t *testing.T) {
+	memFS := vfs.NewMemory()
+	memFS.MkdirAll("/app")
+	memFS.WriteFile("/app/util.bl", []byte(`export let x = 1;`))
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "util.bl"), []byte(`export let x = 2;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.bl"), []byte(`export let x = 3;`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sandbox, err := vfs.Sandbox(root)
+	if err != nil {
+		t.Fatalf("Sandbox returned error: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		fsys     vfs.FS
+		file     string
+		expected string
+	}{
+		{`import "util"; util.x`, nil, "/app/main.bl", "PermissionError: filesystem access is not enabled"},
+		{`import "` + filepath.Join(outside, "secret") + `"`, nil, "", "PermissionError: filesystem access is not enabled"},
+		{`import "util"; util.x`, memFS, "/app/main.bl", "1"},
+		{`import "util"; util.x`, sandbox, filepath.Join(root, "main.bl"), "2"},
+		{`import "` + filepath.Join(outside, "secret") + `"`, sandbox, filepath.Join(root, "main.bl"), "PermissionError: read " + filepath.Join(outside, "secret.bl") + ": path escapes the allowed roots"},
+	}
+
+	for _, tc := range tests {
+		opts := Options{FS: tc.fsys, File: tc.file}
+
+		program := parser.New(lexer.New(tc.input)).Parse()
+		evaluated := EvalContext(context.Background(), program, object.NewEnvironment(), opts)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
+
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`user.name`, "ann"},
+		{`user.tags.len()`, "2"},
+		{`user.missing`, "null"},
+		{`user.keys()`, "[len, name, tags]"},
+		{`user.len`, "7"},
+		{`user.values().len()`, "3"},
+		{`user.has("name")`, "true"},
+		{`user.has("age")`, "false"},
+		{`len(user)`, "3"},
+		{`"  Hello ".trim().upper()`, "HELLO"},
+		{`"a,b,c".split(",").len()`, "3"},
+		{`"ab".repeat(2)`, "abab"},
+		{`"%s-%d".format("x", 1)`, "x-1"},
+		{`[3, 1, 2].sort().map(fn(x) { x * 10 }).join(" ")`, "10 20 30"},
+		{`[1, 2].append(3)`, "[1, 2, 3]"},
+		{`let up = "abc".upper; up()`, "ABC"},
+		{`try { throw "bad" } catch (e) { e.kind + ": " + e.message }`, "Error: bad"},
+		{`try { throw "bad" } catch (e) { e.cause }`, "null"},
+		{`(5).len()`, "NameError: INTEGER has no member len"},
+		{`"a".nope()`, "NameError: STRING has no member nope"},
+		{`"a".repeat("x")`, "TypeError: invalid argument: second argument for repeat must be an integer. got=x (STRING)"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		user, err := ToObject(map[string]any{"name": "ann", "tags": []string{"a", "b"}, "len": 7})
+		if err != nil {
+			t.Fatalf("ToObject returned error: %v", err)
+		}
+		env.Set("user", user)
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y }; Point(1, 2)`, "Point{x: 1, y: 2}"},
+		{`struct Point { x, y }; Point`, "<struct Point>"},
+		{`struct Point { x, y }; let p = Point(1, [2]); p.y[0] + p.x`, "3"},
+		{`struct Point { x, y }; let p = Point(1, 2); p.x = 10; p`, "Point{x: 10, y: 2}"},
+		{`struct Point { x, y }; let p = Point(1, 2); let q = p; q.x = p.y = 5; p`, "Point{x: 5, y: 5}"},
+		{`struct Point { x, y }; Point(1, 2) == Point(1, 2)`, "true"},
+		{`struct Point { x, y }; Point(1, [2]) == Point(1, [3])`, "false"},
+		{`struct Point { x, y }; struct Pair { x, y }; Point(1, 2) == Pair(1, 2)`, "false"},
+		{`struct Unit {}; Unit()`, "Unit{}"},
+		{`struct Node { value, next }; let list = Node(1, Node(2, 0)); list.next.value`, "2"},
+		{`struct Point { x, y }; map([1, 2], fn(x) { Point(x, x) })`, "[Point{x: 1, y: 1}, Point{x: 2, y: 2}]"},
+		{`struct Point { x, y }; json_encode(Point(1, "a"))`, `{"x":1,"y":"a"}`},
+		{`struct Point { x, y }; Point(1, 2).z`, "NameError: Point has no field z"},
+		{`struct Point { x, y }; let p = Point(1, 2); p.z = 3`, "NameError: Point has no field z"},
+		{`struct Point { x, y }; Point(1)`, "ArgumentError: wrong number of arguments to Point. got=1, want=2"},
+		{`let s = "a"; s.x = 1`, "TypeError: cannot assign to member x of a (STRING)"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; p`, "P{x: P{...}, y: 2}"},
+		{`struct P { x, y }; let p = P(1, 2); p.y = [p, p]; p`, "P{x: 1, y: [P{...}, P{...}]}"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; [p.x.x.y, format("%v", p)]`, "[2, P{x: P{...}, y: 2}]"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; let q = P(1, 2); q.x = q; p == q`, "true"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; let q = P(1, 3); q.x = q; p == q`, "false"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; let q = P(p, 2); p == q`, "true"},
+		{`struct P { x, y }; let p = P(1, 2); p.x = p; json_encode(p)`, "ArgumentError: invalid argument: cyclic structure can't be encoded as JSON"},
+	}
+
+	for _, tc := range tests {
+		testResult(t, tc.input, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	describe := `let describe = fn(v) {
+  match (v) {
+    0 => "zero",
+    -1 => "minus one",
+    "hi" => "greeting",
+    true => "yes",
+    [] => "empty",
+    [x] => { let y = x * 2; y }
+    [first, ...rest] if len(rest) > 1 => format("%d then %d more", first, len(rest)),
+    [first, ...rest] => rest,
+    {"type": "user", "id": id} => format("user %d", id),
+    {name} => name,
+    n if n > 0 => "positive",
+    _ => "other"
+  }
+};
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{describe + `describe(0)`, "zero"},
+		{describe + `describe(-1)`, "minus one"},
+		{describe + `describe("hi")`, "greeting"},
+		{describe + `describe(true)`, "yes"},
+		{describe + `describe([])`, "empty"},
+		{describe + `describe([4])`, "8"},
+		{describe + `describe([1, 2, 3])`, "1 then 2 more"},
+		{describe + `describe([1, 2])`, "[2]"},
+		{describe + `describe(5)`, "positive"},
+		{describe + `describe(-5)`, "other"},
+		{describe + `describe(person)`, "ann"},
+		{describe + `describe(user)`, "user 7"},
+		{`match (user) { {"type": "admin"} => 1, {"type": t, id} => t + " " + format("%d", id) }`, "user 7"},
+		{`match (3) { x if x > 5 => "big", x => x }`, "3"},
+		{`let x = 1; match (2) { x => x }; x`, "1"},
+		{`match ([1, [2, 3]]) { [a, [b, c]] => a + b + c }`, "6"},
+		{`match ([1, 2]) { [a, b, c] => 0, [a, ...b] => b }`, "[2]"},
+		{`match ("a") { "b" => 1, _ => 2 }`, "2"},
+		{`match ([1, [2]]) { [1, [2]] => "same" }`, "same"},
+		{`struct Point { x, y }; match (Point(1, 2)) { {x, y} => x + y }`, "3"},
+		{`struct Point { x, y }; match (Point(1, 2)) { {z} => 0, _ => 1 }`, "1"},
+		{`match (3) { 1 => 2 }`, "MatchError: no pattern matched 3 (INTEGER)"},
+		{`match ([1]) { [] => 0, [a, b] => 1 }`, "MatchError: no pattern matched [1] (ARRAY)"},
+		{`match (1) { x if y => 1 }`, "NameError: identifier not found: y"},
+		{`try { match (1) { 2 => 2 } } catch (e) { e.kind }`, "MatchError"},
+	}
+
+	hashes := map[string]any{
+		"person": map[string]any{"name": "ann", "age": 3},
+		"user":   map[string]any{"type": "user", "id": 7},
+		"admin":  map[string]any{"type": "admin"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		for name, hash := range hashes {
+			obj, err := ToObject(hash)
+			if err != nil {
+				t.Fatalf("ToObject returned error: %v", err)
+			}
+			env.Set(name, obj)
+		}
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b, ...rest] = [1, 2, 3, 4]; [a, b, rest]`, "[1, 2, [3, 4]]"},
+		{`let [a, ...rest] = [1]; rest`, "[]"},
+		{`let [_, [b, c]] = [1, [2, 3]]; b + c`, "5"},
+		{`let {name, age} = user; name + format("%d", age)`, "ann3"},
+		{`let {tags: [first, ...others]} = user; first`, "a"},
+		{`struct Point { x, y }; let {x, y} = Point(1, 2); x + y`, "3"},
+		{`let swap = fn([x, y]) { [y, x] }; swap([1, 2])`, "[2, 1]"},
+		{`let name = fn({name}) { name }; name(user)`, "ann"},
+		{`let f = fn(_, x) { x }; f(1, 2)`, "2"},
+		{`map([[1, 2], [3, 4]], fn([a, b]) { a * b })`, "[2, 12]"},
+		{`let [a, b] = [1];`, "MatchError: cannot destructure let [a, b]: [1] has 1 elements, want 2"},
+		{`let [a, ...b] = [];`, "MatchError: cannot destructure let [a, ...b]: [] has 0 elements, want at least 1"},
+		{`let {missing} = user;`, `MatchError: cannot destructure let {missing}: {age: 3, name: ann, tags: [a, b]} has no key "missing"`},
+		{`let [a] = 5;`, "MatchError: cannot destructure let [a]: 5 (INTEGER) is not an array"},
+		{`let f = fn([x, y]) { x }; f(5)`, "MatchError: cannot destructure parameter [x, y] of f: 5 (INTEGER) is not an array"},
+		{`let f = fn({a}) { a }; f([1])`, "MatchError: cannot destructure parameter {a} of f: [1] (ARRAY) is not a hash or struct"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		user, err := ToObject(map[string]any{"name": "ann", "age": 3, "tags": []string{"a", "b"}})
+		if err != nil {
+			t.Fatalf("ToObject returned error: %v", err)
+		}
+		env.Set("user", user)
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
+
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(x, y = 10) { x + y }; f(1)`, "11"},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2)`, "3"},
+		{`let f = fn(x, y = x * 2) { y }; f(4)`, "8"},
+		{`let n = 0; let f = fn(x = n + 1) { x }; let n = 5; f()`, "6"},
+		{`let f = fn(first, ...others) { [first, others] }; f(1, 2, 3)`, "[1, [2, 3]]"},
+		{`let f = fn(first, ...others) { others }; f(1)`, "[]"},
+		{`let f = fn(...all) { len(all) }; f()`, "0"},
+		{`let f = fn(a, b, c) { a + b + c }; let xs = [1, 2, 3]; f(...xs)`, "6"},
+		{`let f = fn(a, b, c) { a + b + c }; f(1, ...[2], 3)`, "6"},
+		{`let f = fn(a, ...rest) { rest }; f(...[1, 2], ...[3])`, "[2, 3]"},
+		{`len(...["abc"])`, "3"},
+		{`let f = fn(x, y = 2, z = 3) { [x, y, z] }; f(1, z: 30)`, "[1, 2, 30]"},
+		{`let f = fn(x, y) { x - y }; f(y: 1, x: 10)`, "9"},
+		{`let f = fn(x, y = 10) { x + y }; f()`, "ArgumentError: wrong number of arguments to f. got=0, want=1 to 2"},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2, 3)`, "ArgumentError: wrong number of arguments to f. got=3, want=1 to 2"},
+		{`let f = fn(x, ...rest) { x }; f()`, "ArgumentError: wrong number of arguments to f. got=0, want>=1"},
+		{`let f = fn(x, y) { x }; f(...[1, 2, 3])`, "ArgumentError: wrong number of arguments to f. got=3, want=2"},
+		{`let f = fn(x) { x }; f(...5)`, "TypeError: invalid argument: 5 (INTEGER) can't be spread, want an array"},
+		{`let f = fn(x) { x }; f(y: 1)`, "ArgumentError: invalid argument: f has no parameter y"},
+		{`let f = fn(x) { x }; f(1, x: 1)`, "ArgumentError: invalid argument: f got multiple values for x"},
+		{`let f = fn(x, y) { x }; f(y: 1)`, "ArgumentError: invalid argument: f is missing an argument for x"},
+		{`len(x: "a")`, "ArgumentError: invalid argument: BUILTIN doesn't accept named arguments"},
+		{`let f = fn(x = y) { x }; f()`, "NameError: identifier not found: y"},
+		{`fn(x, y = 1, ...zs) { x }`, "<fn/1+>"},
+	}
+
+	for _, tc := range tests {
+		testResult(t, tc.input, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestNamedFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn fib(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(10)`, "55"},
+		{`fn fib(n) { n }; fib`, "<fn fib/1>"},
+		{`let f = fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; let fact = 0; f(5)`, "120"},
+		{`let f = fn inner() { 1 }; [f, inner]`, "NameError: identifier not found: inner"},
+		{`let g = fn inner() { 1 }; g`, "<fn inner/0>"},
+		{`let add = fn(x, y) { x + y }; add`, "<fn add/2>"},
+		{`fn(x) { x }`, "<fn/1>"},
+		{`fn f(x, y = 1) { x }; f`, "<fn f/1-2>"},
+		{`fn f(...xs) { xs }; f`, "<fn f/0+>"},
+		{`map([3, 4], fn double(x) { x * 2 })`, "[6, 8]"},
+		{`fn f(x) { x }; f()`, "ArgumentError: wrong number of arguments to f. got=0, want=1"},
+		{`fn(x) { x }()`, "ArgumentError: wrong number of arguments to anonymous function. got=0, want=1"},
+	}
+
+	for _, tc := range tests {
+		testResult(t, tc.input, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestArrowFunctionsAndPipelines(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let double = x => x * 2; double(21)`, "42"},
+		{`let add = (a, b) => a + b; add(1, 2)`, "3"},
+		{`(() => 7)()`, "7"},
+		{`map([1, 2], x => { let y = x + 1; y * 10 })`, "[20, 30]"},
+		{`let adder = x => y => x + y; adder(1)(2)`, "3"},
+		{`sort([3, 1, 2], (a, b) => a > b)`, "[3, 2, 1]"},
+		{`let double = x => x * 2; double`, "<fn double/1>"},
+		{`[1, 2, 3, 4] |> filter(x => x > 2) |> map(x => x * 10)`, "[30, 40]"},
+		{`"abc" |> upper |> len`, "3"},
+		{`[3, 1] |> len() > 1`, "true"},
+		{`1 + 2 |> (x => x * 2)`, "6"},
+		{`let add = x => y => x + y; 2 |> (add(1))`, "3"},
+		{`"a-b" |> split("-") |> join("+")`, "a+b"},
+		{`match (3) { x if any([1, 3], y => y == x) => "found", _ => "missing" }`, "found"},
+		{`let ok = true; match (3) { x if ok => "ok", _ => "no" }`, "ok"},
+		{`5 |> 3`, "TypeError: not a function: INTEGER"},
+		{`let f = x => x; f()`, "ArgumentError: wrong number of arguments to f. got=0, want=1"},
+	}
+
+	for _, tc := range tests {
+		testResult(t, tc.input, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestConditionalAndNullOperators(t *testing.T) {
+	prelude := `let nothing = if (false) { 0 };
+let parse = fn(x) { if (x < 0) { error("ValueError", "negative") } else { x } };
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`true ? 1 : 2`, "1"},
+		{`nothing ? 1 : 2`, "2"},
+		{`1 > 2 ? "a" : 2 > 1 ? "b" : "c"`, "b"},
+		{`let x = 5; x > 3 ? -1 : 1`, "-1"},
+		{`false ? boom() : 3`, "3"},
+		{`nothing ?? 5`, "5"},
+		{`0 ?? 5`, "0"},
+		{`false ?? boom()`, "false"},
+		{`nothing ?? nothing ?? "last"`, "last"},
+		{`user?.name`, "ann"},
+		{`user?.missing ?? "default"`, "default"},
+		{`nothing?.name`, "null"},
+		{`nothing?.a.b.c()`, "null"},
+		{`nothing?.[0]`, "null"},
+		{`nothing?.[1:]`, "null"},
+		{`[1, 2]?.[1]`, "2"},
+		{`[1, 2, 3]?.[1:]`, "[2, 3]"},
+		{`"abc"?.upper()`, "ABC"},
+		{`user?.tags?.[5] ?? "none"`, "none"},
+		{`let double = fn(x) { parse(x)? * 2 }; double(2)`, "4"},
+		{`let dec = fn(x) { parse(x)? - 1 }; dec(5)`, "4"},
+		{`let dec = fn(x) { parse(x)? - 1 }; dec(-5)`, "ValueError: negative"},
+		{"let x = 1; let f = fn() {\n  parse(x)?\n  x + 1\n}; f()", "2"},
+		{"let f = fn(x) {\n  parse(x)?\n  x > 1 ? x : 0\n}; [f(2), f(1)]", "[2, 0]"},
+		{`nothing.name`, "NameError: NULL has no member name"},
+		{`user?.name.nope`, "NameError: STRING has no member nope"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		user, err := ToObject(map[string]any{"name": "ann", "tags": []string{"a"}})
+		if err != nil {
+			t.Fatalf("ToObject returned error: %v", err)
+		}
+		env.Set("user", user)
+
+		evaluated := Eval(parser.New(lexer.New(prelude+tc.input)).Parse(), env)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
+
+func TestConstAndFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const x = 1; x`, "1"},
+		{`const [a, ...rest] = [1, 2, 3]; rest`, "[2, 3]"},
+		{`const x = 1; let f = fn() { let x = 2; x }; f() + x`, "3"},
+		{`const x = 1; let f = fn() { const x = 2; x }; f()`, "2"},
+		{`const x = 1; let x = 2;`, "NameError: cannot reassign constant x"},
+		{`const x = 1; const x = 2;`, "NameError: cannot reassign constant x"},
+		{`let x = 1; const x = 2;`, "NameError: cannot declare constant x, it is already declared in this scope"},
+		{`const f = 1; fn f() { 2 }`, "NameError: cannot reassign constant f"},
+		{`const Point = 1; struct Point { x }`, "NameError: cannot reassign constant Point"},
+		{`const [a, b] = [1, 2]; let [b, c] = [3, 4];`, "NameError: cannot reassign constant b"},
+		{`let [a, b] = [1]; a`, "MatchError: cannot destructure let [a, b]: [1] has 1 elements, want 2"},
+		{`try { let [a, b] = [1]; } catch (e) { 0 }; a`, "NameError: identifier not found: a"},
+		{`let xs = [1, 2, 3]; xs[0] = 10; xs[-1] = 30; xs`, "[10, 2, 30]"},
+		{`let xs = [[1]]; let ys = xs; ys[0][0] = 2; xs`, "[[2]]"},
+		{`let h = user; h.name = "bob"; h["age"] = 4; [h.name, h.age]`, "[bob, 4]"},
+		{`struct Point { x, y }; let p = Point(1, 2); p.x = 5; p`, "Point{x: 5, y: 2}"},
+		{`let xs = [1]; xs[1] = 2`, "IndexError: index 1 out of range for array of length 1"},
+		{`let xs = [1]; xs["a"] = 2`, "IndexError: invalid argument: index a (STRING) is not an integer"},
+		{`let h = user; h[[1]] = 2`, "IndexError: invalid argument: [1] (ARRAY) can't be used as a hash key"},
+		{`struct Point { x, y }; let p = Point(1, 2); p[0] = 5`, "TypeError: cannot assign to an index of Point{x: 1, y: 2} (STRUCT)"},
+		{`let s = "abc"; s[0] = "x"`, "TypeError: cannot assign to an index of abc (STRING)"},
+		{`const xs = freeze([1, [2]]); xs`, "[1, [2]]"},
+		{`let xs = freeze([1, [2]]); [is_frozen(xs), is_frozen(xs[1]), is_frozen([])]`, "[true, true, false]"},
+		{`let xs = freeze([1, [2]]); xs[0] = 5`, "TypeError: cannot modify frozen [1, [2]] (ARRAY)"},
+		{`let xs = freeze([1, [2]]); xs[1][0] = 5`, "TypeError: cannot modify frozen [2] (ARRAY)"},
+		{`let h = freeze(user); h.name = "bob"`, `TypeError: cannot modify frozen {age: 3, name: ann} (HASH)`},
+		{`struct Point { x, y }; let p = freeze(Point(1, [2])); p.x = 5`, "TypeError: cannot modify frozen Point{x: 1, y: [2]} (STRUCT)"},
+		{`let xs = [1]; xs[0] = xs; freeze(xs); is_frozen(xs)`, "true"},
+		{`freeze(5)`, "5"},
+		{`let xs = freeze([3, 1, 2]); sort(xs)`, "[1, 2, 3]"},
+		{`const x = 5; -x; x`, "5"},
+		{`const x = 5; [-x, x]`, "[-5, 5]"},
+		{`let a = freeze([1, 2]); -a[0]; a`, "[1, 2]"},
+		{`let h = freeze(user); -h.age; h.age`, "3"},
+		{`let a = freeze([1, 2, 3]); let b = append(a, 4); b[0] = 9; [a, b, is_frozen(b)]`, "[[1, 2, 3], [9, 2, 3, 4], false]"},
+		{`let a = append([1, 2], 3); let b = append(a, 4); let c = append(a, 5); [b, c]`, "[[1, 2, 3, 4], [1, 2, 3, 5]]"},
+		{`let a = [1]; let b = append(a); b[0] = 2; a`, "[1]"},
+		{`let a = [1]; a[0] = a; a`, "[[...]]"},
+		{`let a = [1, 2]; a[1] = [a]; a`, "[1, [[...]]]"},
+		{`let h = user; h.self = h; h`, "{age: 3, name: ann, self: {...}}"},
+		{`let a = [1]; a[0] = a; let b = [1]; b[0] = b; [a == b, a == [a], a < b, len(sort([a, b]))]`, "[true, true, false, 2]"},
+		{`let a = [1, 2]; a[0] = a; let b = [1, 3]; b[0] = b; [a == b, a < b]`, "[false, true]"},
+		{`let h = user; h.self = h; json_encode(h)`, "ArgumentError: invalid argument: cyclic structure can't be encoded as JSON"},
+	}
+
+	for _, tc := range tests {
+		env := object.NewEnvironment()
+		user, err := ToObject(map[string]any{"name": "ann", "age": 3})
+		if err != nil {
+			t.Fatalf("ToObject returned error: %v", err)
+		}
+		env.Set("user", user)
+
+		evaluated := Eval(parser.New(lexer.New(tc.input)).Parse(), env)
+		testResult(t, tc.input, evaluated, tc.expected)
+	}
+}
@@ -0,0 +1,171 @@
+package evaluator
+
+import (
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrNoMember             = "%s has no member %s"
+	ErrNoField              = "%s has no field %s"
+	ErrInvalidAssignee      = "cannot assign to member %s of %s (%s)"
+	ErrInvalidIndexAssignee = "cannot assign to an index of %s (%s)"
+	ErrIndexOutOfRange      = "index %d out of range for array of length %d"
+	ErrFrozen               = "cannot modify frozen %s (%s)"
+)
+
+// methods holds the methods of the built-in types. A method is a builtin that
+// gets its receiver as the first argument, so "ab".repeat(2) calls repeat with
+// "ab" and 2.
+var methods = map[object.ObjectType]map[string]object.BuiltinFn{
+	object.STRING: {
+		"len":         builtins["len"].Fn,
+		"split":       builtinSplit,
+		"trim":        builtins["trim"].Fn,
+		"trim_left":   builtins["trim_left"].Fn,
+		"trim_right":  builtins["trim_right"].Fn,
+		"upper":       builtins["upper"].Fn,
+		"lower":       builtins["lower"].Fn,
+		"contains":    builtins["contains"].Fn,
+		"starts_with": builtins["starts_with"].Fn,
+		"ends_with":   builtins["ends_with"].Fn,
+		"index_of":    builtinIndexOf,
+		"replace":     builtinReplace,
+		"repeat":      builtinRepeat,
+		"substr":      builtinSubstr,
+		"pad_left":    builtins["pad_left"].Fn,
+		"pad_right":   builtins["pad_right"].Fn,
+		"chars":       builtinChars,
+		"format":      builtinFormat,
+	},
+	object.ARRAY: {
+		"len":      builtins["len"].Fn,
+		"append":   builtins["append"].Fn,
+		"join":     builtinJoin,
+		"map":      builtinMap,
+		"filter":   builtinFilter,
+		"reduce":   builtinReduce,
+		"each":     builtinEach,
+		"find":     builtinFind,
+		"any":      builtinAny,
+		"all":      builtinAll,
+		"sort":     builtinSort,
+		"zip":      builtinZip,
+		"flat_map": builtinFlatMap,
+	},
+	object.HASH: {
+		"len":    builtins["len"].Fn,
+		"keys":   builtinKeys,
+		"values": builtinValues,
+		"has":    builtinHas,
+	},
+}
+
+// evalMember looks up name on obj. Hash keys, module exports and error fields
+// come first, then the methods of obj's type. A hash without the key or the
+// method gives null like indexing does.
+func evalMember(obj object.Object, name string) object.Object {
+	key := &object.String{Value: name}
+
+	switch obj := obj.(type) {
+	case *object.Hash:
+		if val, exists := obj.Get(key); exists {
+			return val
+		}
+	case *object.Module:
+		return evalModuleMemberExpression(obj, key)
+	case *object.Error:
+		return evalErrorFieldExpression(obj, key)
+	case *object.Struct:
+		if i := obj.StructType.FieldIndex(name); i >= 0 {
+			return obj.Values[i]
+		}
+		return newError(object.NAME_ERROR, ErrNoField, obj.StructType.Name, name)
+	}
+
+	if method, exists := methods[obj.Type()][name]; exists {
+		return bindMethod(obj, method)
+	}
+	if obj.Type() == object.HASH {
+		return NULL
+	}
+	return newError(object.NAME_ERROR, ErrNoMember, obj.Type(), name)
+}
+
+// evalAssignExpression sets a struct field, a hash key or an array element.
+// Frozen values can't be assigned to.
+func (e *evaluator) evalAssignExpression(n *ast.AssignExpression, env *object.Environment) object.Object {
+	var objExpr, keyExpr ast.Expression
+	switch target := n.Target.(type) {
+	case *ast.MemberExpression:
+		objExpr = target.Object
+	case *ast.IndexExpression:
+		objExpr, keyExpr = target.Left, target.Index
+	}
+
+	obj := e.eval(objExpr, env)
+	if isUnwinding(obj) {
+		return obj
+	}
+	var key object.Object
+	if member, isMember := n.Target.(*ast.MemberExpression); isMember {
+		key = &object.String{Value: member.Property.Value}
+	} else if key = e.eval(keyExpr, env); isUnwinding(key) {
+		return key
+	}
+	val := e.eval(n.Value, env)
+	if isUnwinding(val) {
+		return val
+	}
+
+	if isFrozen(obj) {
+		return newError(object.TYPE_ERROR, ErrFrozen, obj.Inspect(), obj.Type())
+	}
+
+	switch obj := obj.(type) {
+	case *object.Struct:
+		if _, isIndex := n.Target.(*ast.IndexExpression); isIndex {
+			return newError(object.TYPE_ERROR, ErrInvalidIndexAssignee, obj.Inspect(), obj.Type())
+		}
+		name := key.(*object.String)
+		i := obj.StructType.FieldIndex(name.Value)
+		if i < 0 {
+			return newError(object.NAME_ERROR, ErrNoField, obj.StructType.Name, name.Value)
+		}
+		obj.Values[i] = val
+	case *object.Hash:
+		hashable, isHashable := key.(object.Hashable)
+		if !isHashable {
+			return newError(object.INDEX_ERROR, ErrUnhashableKey, key.Inspect(), key.Type())
+		}
+		obj.Set(hashable, val)
+	case *object.Array:
+		idx, isInt := key.(*object.Integer)
+		if !isInt {
+			return newError(object.INDEX_ERROR, ErrInvalidIndex, key.Inspect(), key.Type())
+		}
+		i := idx.Value
+		if i < 0 {
+			i += int64(len(obj.Elems))
+		}
+		if i < 0 || i >= int64(len(obj.Elems)) {
+			return newError(object.INDEX_ERROR, ErrIndexOutOfRange, idx.Value, len(obj.Elems))
+		}
+		obj.Elems[i] = val
+	default:
+		if member, isMember := n.Target.(*ast.MemberExpression); isMember {
+			return newError(object.TYPE_ERROR, ErrInvalidAssignee, member.Property.Value, obj.Inspect(), obj.Type())
+		}
+		return newError(object.TYPE_ERROR, ErrInvalidIndexAssignee, obj.Inspect(), obj.Type())
+	}
+
+	return val
+}
+
+// bindMethod returns a builtin that calls method with recv in front of its
+// arguments.
+func bindMethod(recv object.Object, method object.BuiltinFn) *object.Builtin {
+	return &object.Builtin{Fn: func(c object.CallContext, args ...object.Object) object.Object {
+		return method(c, append([]object.Object{recv}, args...)...)
+	}}
+}
@@ -0,0 +1,160 @@
+package evaluator
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// ModuleExt is added to import paths that have no extension.
+const ModuleExt = ".bl"
+
+const (
+	ErrModuleNotFound = "module not found: %s"
+	ErrModuleParse    = "could not parse module %s: %s"
+	ErrImportCycle    = "import cycle: %s"
+	ErrNoExport       = "module %s has no export %s"
+)
+
+// ModuleLoader finds, evaluates and caches imported modules. Each module is
+// evaluated once, in its own environment. A loader is not safe for concurrent
+// use.
+type ModuleLoader struct {
+	// SearchPath lists the directories tried, in order, for imports that
+	// aren't found next to the importing file.
+	SearchPath []string
+	// ReadFile reads a module's source. Nil means reading through
+	// Options.FS, so imports are denied when the host grants no filesystem.
+	// Set it to os.ReadFile to let scripts import any file on the host.
+	ReadFile func(name string) ([]byte, error)
+
+	modules map[string]*object.Module
+	loading []string // the modules being evaluated, outermost first
+}
+
+func NewModuleLoader(searchPath ...string) *ModuleLoader {
+	return &ModuleLoader{SearchPath: searchPath, modules: make(map[string]*object.Module)}
+}
+
+// resolve finds the file that importer means by p, reading it with readFile.
+// The source is only read for modules that aren't loaded or being loaded yet.
+func (l *ModuleLoader) resolve(importer, p string, readFile func(string) ([]byte, error)) (string, []byte, error) {
+	if filepath.Ext(p) == "" {
+		p += ModuleExt
+	}
+
+	candidates := []string{p}
+	if !filepath.IsAbs(p) {
+		candidates = []string{filepath.Join(filepath.Dir(importer), p)}
+		for _, dir := range l.SearchPath {
+			candidates = append(candidates, filepath.Join(dir, p))
+		}
+	}
+
+	for _, name := range candidates {
+		name = filepath.Clean(name)
+		if _, loaded := l.modules[name]; loaded || slices.Contains(l.loading, name) {
+			return name, nil, nil
+		}
+
+		src, err := readFile(name)
+		if err == nil {
+			return name, src, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", nil, err
+		}
+	}
+
+	return "", nil, fs.ErrNotExist
+}
+
+// importModule returns the module that the file being evaluated means by p,
+// evaluating it if this is its first import.
+func (e *evaluator) importModule(p string) object.Object {
+	l := e.opts.Modules
+
+	readFile := l.ReadFile
+	if readFile == nil {
+		if e.opts.FS == nil {
+			return newError(object.PERMISSION_ERROR, ErrNoFilesystem)
+		}
+		readFile = e.opts.FS.ReadFile
+	}
+
+	name, src, err := l.resolve(e.file, p, readFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return newError(object.IMPORT_ERROR, ErrModuleNotFound, p)
+	case err != nil:
+		return fsError(err)
+	}
+
+	if i := slices.Index(l.loading, name); i >= 0 {
+		cycle := append(slices.Clone(l.loading[i:]), name)
+		return newError(object.IMPORT_ERROR, ErrImportCycle, strings.Join(cycle, " -> "))
+	}
+	if mod, loaded := l.modules[name]; loaded {
+		return mod
+	}
+
+	prs := parser.New(lexer.New(string(src)))
+	program := prs.Parse()
+	if len(prs.Errs()) != 0 {
+		return newError(object.IMPORT_ERROR, ErrModuleParse, name, strings.Join(prs.Errs(), "; "))
+	}
+
+	mod := &object.Module{Path: name, Exports: make(map[string]object.Object)}
+
+	l.loading = append(l.loading, name)
+	file, outer := e.file, e.module
+	e.file, e.module = name, mod
+	defer func() {
+		l.loading = l.loading[:len(l.loading)-1]
+		e.file, e.module = file, outer
+	}()
+
+	if res := e.evalModule(program.Statements, object.NewEnvironment()); isUnwinding(res) {
+		return res
+	}
+
+	l.modules[name] = mod
+	return mod
+}
+
+// evalModule runs the top level of a module. Unlike evalProgram it leaves a
+// thrown error unwinding into the importer.
+func (e *evaluator) evalModule(stmts []ast.Statement, env *object.Environment) object.Object {
+	if exc := e.tick(); exc != nil {
+		return exc
+	}
+
+	for _, s := range stmts {
+		res := e.eval(s, env)
+		if _, isExc := res.(*object.Exception); isExc {
+			return res
+		}
+		if _, isRetVal := res.(*object.ReturnValue); isRetVal {
+			break
+		}
+	}
+	return nil
+}
+
+func evalModuleMemberExpression(left, idx object.Object) object.Object {
+	mod := left.(*object.Module)
+	name := idx.(*object.String).Value
+
+	val, exists := mod.Exports[name]
+	if !exists {
+		return newError(object.NAME_ERROR, ErrNoExport, mod.Path, name)
+	}
+	return val
+}
@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/object"
+)
+
+const (
+	ErrNoMatch     = "no pattern matched %s (%s)"
+	ErrDestructure = "cannot destructure %s: %s"
+)
+
+// matchPattern matches val against p and binds the names in p in env. It
+// returns an empty string on success, otherwise a description of why val
+// doesn't fit p. Names bound before the mismatch stay bound, so callers
+// should match into an environment they can throw away.
+func (e *evaluator) matchPattern(p ast.Pattern, val object.Object, env *object.Environment) string {
+	switch p := p.(type) {
+	case *ast.WildcardPattern:
+		return ""
+	case *ast.BindingPattern:
+		env.Set(p.Name.Value, val)
+		return ""
+	case *ast.LiteralPattern:
+		lit := e.eval(p.Value, env)
+		if !objectsEqual(lit, val) {
+			return fmt.Sprintf("%s (%s) is not %s", val.Inspect(), val.Type(), lit.Inspect())
+		}
+		return ""
+	case *ast.ArrayPattern:
+		return e.matchArrayPattern(p, val, env)
+	case *ast.HashPattern:
+		return e.matchHashPattern(p, val, env)
+	default:
+		return fmt.Sprintf("unknown pattern %s", p.String())
+	}
+}
+
+func (e *evaluator) matchArrayPattern(p *ast.ArrayPattern, val object.Object, env *object.Environment) string {
+	arr, isArr := val.(*object.Array)
+	if !isArr {
+		return fmt.Sprintf("%s (%s) is not an array", val.Inspect(), val.Type())
+	}
+
+	switch {
+	case p.Rest == nil && len(arr.Elems) != len(p.Elems):
+		return fmt.Sprintf("%s has %d elements, want %d", arr.Inspect(), len(arr.Elems), len(p.Elems))
+	case p.Rest != nil && len(arr.Elems) < len(p.Elems):
+		return fmt.Sprintf("%s has %d elements, want at least %d", arr.Inspect(), len(arr.Elems), len(p.Elems))
+	}
+
+	for i, elem := range p.Elems {
+		if reason := e.matchPattern(elem, arr.Elems[i], env); reason != "" {
+			return reason
+		}
+	}
+
+	if p.Rest != nil {
+		rest := make([]object.Object, len(arr.Elems)-len(p.Elems))
+		copy(rest, arr.Elems[len(p.Elems):])
+		return e.matchPattern(p.Rest, &object.Array{Elems: rest}, env)
+	}
+
+	return ""
+}
+
+// matchHashPattern matches hashes by string key and structs by field name.
+func (e *evaluator) matchHashPattern(p *ast.HashPattern, val object.Object, env *object.Environment) string {
+	var lookup func(key string) (object.Object, bool)
+	switch val := val.(type) {
+	case *object.Hash:
+		lookup = func(key string) (object.Object, bool) {
+			return val.Get(&object.String{Value: key})
+		}
+	case *object.Struct:
+		lookup = func(key string) (object.Object, bool) {
+			i := val.StructType.FieldIndex(key)
+			if i < 0 {
+				return nil, false
+			}
+			return val.Values[i], true
+		}
+	default:
+		return fmt.Sprintf("%s (%s) is not a hash or struct", val.Inspect(), val.Type())
+	}
+
+	for _, pair := range p.Pairs {
+		field, exists := lookup(pair.Key)
+		if !exists {
+			return fmt.Sprintf("%s has no key %q", val.Inspect(), pair.Key)
+		}
+		if reason := e.matchPattern(pair.Value, field, env); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+// evalMatchExpression evaluates the body of the first arm that matches. Each
+// arm binds its names in a fresh scope, so a partial match can't leak
+// bindings into the next arm.
+func (e *evaluator) evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := e.eval(me.Subject, env)
+	if isUnwinding(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewLocalEnvironment(env)
+		if e.matchPattern(arm.Pattern, subject, armEnv) != "" {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := e.eval(arm.Guard, armEnv)
+			if isUnwinding(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return e.eval(arm.Body, armEnv)
+	}
+
+	return newError(object.MATCH_ERROR, ErrNoMatch, subject.Inspect(), subject.Type())
+}
+
+// destructure binds val to the names in p for a let or a parameter, which
+// have no other arm to fall back on. what names the binding in the error.
+func (e *evaluator) destructure(p ast.Pattern, val object.Object, env *object.Environment, what string) *object.Exception {
+	if reason := e.matchPattern(p, val, env); reason != "" {
+		return newError(object.MATCH_ERROR, ErrDestructure, what, reason)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package evaluator
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/vfs"
+)
+
+// Registry holds the builtins visible to an evaluation. Names containing dots
+// such as "math.abs" are grouped into namespaces: "math" evaluates to a hash
+// holding "abs". Namespaces are frozen so that scripts can't replace the
+// builtins in them. Bindings in the environment shadow builtins.
+type Registry struct {
+	entries map[string]object.Object
+}
+
+// NewRegistry returns an empty registry, for sandboxes that should only see
+// the builtins they are given.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]object.Object)}
+}
+
+// StdRegistry returns a new registry holding the standard builtins.
+func StdRegistry() *Registry {
+	r := NewRegistry()
+	for name, builtin := range builtins {
+		r.Register(name, builtin.Fn)
+	}
+	return r
+}
+
+// defaultRegistry is used by evaluations that don't supply their own. It is
+// never modified.
+var defaultRegistry = StdRegistry()
+
+// Register binds name to fn, replacing whatever was registered under it.
+func (r *Registry) Register(name string, fn object.BuiltinFn) {
+	r.set(name, &object.Builtin{Fn: fn})
+}
+
+// RegisterFunc binds name to the Go function fn, see NewBuiltin.
+func (r *Registry) RegisterFunc(name string, fn any) error {
+	builtin, err := NewBuiltin(fn)
+	if err != nil {
+		return err
+	}
+	r.set(name, builtin)
+	return nil
+}
+
+func (r *Registry) set(name string, builtin *object.Builtin) {
+	path := strings.Split(name, ".")
+	if len(path) == 1 {
+		r.entries[name] = builtin
+		return
+	}
+
+	ns, isNs := r.entries[path[0]].(*object.Hash)
+	if !isNs {
+		ns = &object.Hash{Pairs: make(map[object.HashKey]object.HashPair), Frozen: true}
+		r.entries[path[0]] = ns
+	}
+
+	for _, part := range path[1 : len(path)-1] {
+		key := &object.String{Value: part}
+		inner, _ := ns.Get(key)
+		innerNs, isNs := inner.(*object.Hash)
+		if !isNs {
+			innerNs = &object.Hash{Pairs: make(map[object.HashKey]object.HashPair), Frozen: true}
+			ns.Set(key, innerNs)
+		}
+		ns = innerNs
+	}
+
+	ns.Set(&object.String{Value: path[len(path)-1]}, builtin)
+}
+
+// Unregister removes the top-level builtin or namespace called name.
+func (r *Registry) Unregister(name string) {
+	delete(r.entries, name)
+}
+
+// Lookup returns the builtin or namespace registered as name.
+func (r *Registry) Lookup(name string) (object.Object, bool) {
+	obj, exists := r.entries[name]
+	return obj, exists
+}
+
+// callContext is handed to builtins so they can reach the evaluation that
+// called them.
+type callContext struct {
+	e   *evaluator
+	env *object.Environment
+}
+
+func (c *callContext) Context() context.Context { return c.e.ctx }
+func (c *callContext) Env() *object.Environment { return c.env }
+func (c *callContext) Out() io.Writer           { return c.e.opts.Stdout }
+func (c *callContext) ErrOut() io.Writer        { return c.e.opts.Stderr }
+func (c *callContext) In() *bufio.Reader        { return c.e.in }
+func (c *callContext) FS() vfs.FS               { return c.e.opts.FS }
+func (c *callContext) Call(fn object.Object, args ...object.Object) object.Object {
+	return c.e.applyFunction(fn, args, c.env)
+}
@@ -0,0 +1,136 @@
+// Package interpreter is the entry point for embedding basedlang in Go
+// programs. It wires the lexer, parser and evaluator together and reports
+// failures as Go errors.
+package interpreter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/lexer"
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/parser"
+)
+
+// Options bounds the work done by every evaluation of an Interpreter.
+type Options = evaluator.Options
+
+// ParseError is returned when the source could not be parsed.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parser errors: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError is returned when evaluation ends with an uncaught error or
+// produces an error value. Err carries the kind, the message and the stack
+// trace.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string { return e.Err.Error() }
+
+func (e *RuntimeError) Unwrap() error { return e.Err }
+
+// Interpreter evaluates basedlang programs against a global environment that
+// persists between calls. It is not safe for concurrent use.
+type Interpreter struct {
+	env  *object.Environment
+	opts Options
+}
+
+// New returns an interpreter with an empty global environment. Unless
+// opts.Builtins is set, it gets its own copy of the standard builtins that
+// Register adds to.
+func New(opts Options) *Interpreter {
+	if opts.Builtins == nil {
+		opts.Builtins = evaluator.StdRegistry()
+	}
+	if opts.Modules == nil {
+		// Keep imported modules loaded between calls
+		opts.Modules = evaluator.NewModuleLoader()
+	}
+	if opts.Stdin != nil {
+		// Buffer once so input read ahead by one Eval isn't lost to the next
+		opts.Stdin = bufio.NewReader(opts.Stdin)
+	}
+	return &Interpreter{env: object.NewEnvironment(), opts: opts}
+}
+
+// Eval parses and evaluates src and returns the value of its last statement.
+func (i *Interpreter) Eval(ctx context.Context, src string) (object.Object, error) {
+	return i.eval(ctx, src, i.opts)
+}
+
+// RunFile evaluates the script at path. Its imports are resolved relative to
+// it.
+func (i *Interpreter) RunFile(ctx context.Context, path string) (object.Object, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := i.opts
+	opts.File = path
+	return i.eval(ctx, string(src), opts)
+}
+
+func (i *Interpreter) eval(ctx context.Context, src string, opts Options) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.Parse()
+	if len(p.Errs()) != 0 {
+		return nil, &ParseError{Errors: p.Errs()}
+	}
+
+	return result(evaluator.EvalContext(ctx, program, i.env, opts))
+}
+
+// SetGlobal binds name to val in the global environment.
+func (i *Interpreter) SetGlobal(name string, val object.Object) {
+	i.env.Set(name, val)
+}
+
+// GetGlobal returns the value bound to name in the global environment.
+func (i *Interpreter) GetGlobal(name string) (object.Object, bool) {
+	return i.env.Get(name)
+}
+
+// Register adds a builtin called name that calls the Go function fn.
+// Arguments and results are converted as described by evaluator.NewBuiltin.
+// Names like "math.abs" are grouped into namespaces.
+func (i *Interpreter) Register(name string, fn any) error {
+	return i.opts.Builtins.RegisterFunc(name, fn)
+}
+
+// RegisterBuiltin adds a builtin called name that works on basedlang values
+// directly and can call back into the program through its call context.
+func (i *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFn) {
+	i.opts.Builtins.Register(name, fn)
+}
+
+// Call calls the global function fnName with args.
+func (i *Interpreter) Call(ctx context.Context, fnName string, args ...object.Object) (object.Object, error) {
+	fn, exists := i.env.Get(fnName)
+	if !exists {
+		return nil, fmt.Errorf("function not found: %s", fnName)
+	}
+
+	return result(evaluator.ApplyContext(ctx, fn, args, i.env, i.opts))
+}
+
+func result(obj object.Object) (object.Object, error) {
+	if errObj, isErr := obj.(*object.Error); isErr {
+		return nil, &RuntimeError{Err: errObj}
+	}
+	if obj == nil {
+		return evaluator.NULL, nil
+	}
+	return obj, nil
+}
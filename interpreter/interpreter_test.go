@@ -0,0 +1,240 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nayyara-airlangga/basedlang/object"
+	"github.com/nayyara-airlangga/basedlang/vfs"
+)
+
+func TestEval(t *testing.T) {
+	interp := New(Options{})
+
+	if _, err := interp.Eval(context.Background(), "let add = fn(x, y) { x + y };"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := interp.Eval(context.Background(), "add(2, 3)")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testIntegerObject(t, res, 5)
+}
+
+func TestEvalErrors(t *testing.T) {
+	interp := New(Options{})
+
+	_, err := interp.Eval(context.Background(), "let = 5;")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error is not ParseError. got=%T (%v)", err, err)
+	}
+	if len(parseErr.Errors) == 0 {
+		t.Errorf("ParseError has no messages")
+	}
+
+	_, err = interp.Eval(context.Background(), "1 + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("error is not RuntimeError. got=%T (%v)", err, err)
+	}
+	if err.Error() != "TypeError: type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
+
+func TestEvalCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := New(Options{}).Eval(ctx, "1")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("error is not RuntimeError. got=%T (%v)", err, err)
+	}
+	if runtimeErr.Err.Kind != object.CANCELED_ERROR {
+		t.Errorf("wrong error kind. expected=%q, got=%q", object.CANCELED_ERROR, runtimeErr.Err.Kind)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	interp := New(Options{})
+	interp.SetGlobal("limit", &object.Integer{Value: 10})
+
+	res, err := interp.Eval(context.Background(), "let doubled = limit * 2;  doubled")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testIntegerObject(t, res, 20)
+
+	doubled, exists := interp.GetGlobal("doubled")
+	if !exists {
+		t.Fatalf("global doubled not found")
+	}
+	testIntegerObject(t, doubled, 20)
+
+	if _, exists := interp.GetGlobal("missing"); exists {
+		t.Errorf("global missing should not exist")
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := New(Options{})
+	if _, err := interp.Eval(context.Background(), "let mul = fn(x, y) { x * y };"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := interp.Call(context.Background(), "mul", &object.Integer{Value: 6}, &object.Integer{Value: 7})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testIntegerObject(t, res, 42)
+
+	if _, err := interp.Call(context.Background(), "mul", &object.Integer{Value: 6}); err == nil {
+		t.Errorf("expected error for wrong number of arguments")
+	}
+	if _, err := interp.Call(context.Background(), "missing"); err == nil {
+		t.Errorf("expected error for missing function")
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.bl")
+	if err := os.WriteFile(path, []byte("let x = 4;\nx * x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := New(Options{}).RunFile(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testIntegerObject(t, res, 16)
+}
+
+func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
+	intObj, isInt := obj.(*object.Integer)
+	if !isInt {
+		t.Errorf("obj is not Integer. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if intObj.Value != expected {
+		t.Errorf("obj.Value is incorrect. expected=%d, got=%d", expected, intObj.Value)
+		return false
+	}
+	return true
+}
+
+func TestRegister(t *testing.T) {
+	interp := New(Options{})
+	err := interp.Register("greet", func(name string, times int) (string, error) {
+		if times < 1 {
+			return "", errors.New("times must be positive")
+		}
+		return strings.Repeat("hi "+name+" ", times), nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := interp.Eval(context.Background(), `greet("ann", 2)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inspect() != "hi ann hi ann " {
+		t.Errorf("wrong result. got=%q", res.Inspect())
+	}
+
+	_, err = interp.Eval(context.Background(), `greet("ann", 0)`)
+	if err == nil || err.Error() != "HostError: times must be positive" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	if err := interp.Register("bad", 42); err == nil {
+		t.Errorf("expected error registering a non-function")
+	}
+}
+
+func TestIsolatedBuiltins(t *testing.T) {
+	first := New(Options{})
+	second := New(Options{})
+
+	if err := first.Register("host.secret", func() string { return "first" }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first.RegisterBuiltin("apply", func(c object.CallContext, args ...object.Object) object.Object {
+		return c.Call(args[0], args[1:]...)
+	})
+
+	res, err := first.Eval(context.Background(), `apply(fn(x) { x + host["secret"]() }, "from ")`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inspect() != "from first" {
+		t.Errorf("wrong result. got=%q", res.Inspect())
+	}
+
+	if _, err := second.Eval(context.Background(), `host["secret"]()`); err == nil {
+		t.Errorf("builtin registered on one interpreter is visible in another")
+	}
+}
+
+func TestStreams(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(Options{Stdin: strings.NewReader("first\nsecond\n"), Stdout: &out})
+
+	for _, src := range []string{`puts(read_line())`, `puts(read_line())`} {
+		if _, err := interp.Eval(context.Background(), src); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if out.String() != "first\nsecond\n" {
+		t.Errorf("wrong output. expected=%q, got=%q", "first\nsecond\n", out.String())
+	}
+}
+
+func TestRunFileImports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.bl":     `import "lib/math" as m; m["square"](5)`,
+		"lib/math.bl": `puts("loaded"); export let square = fn(x) { x * x };`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := New(Options{}).RunFile(context.Background(), filepath.Join(dir, "main.bl")); err == nil || err.Error() != "PermissionError: filesystem access is not enabled" {
+		t.Errorf("imports without a filesystem should fail. got=%v", err)
+	}
+
+	fsys, err := vfs.Sandbox(dir)
+	if err != nil {
+		t.Fatalf("Sandbox returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	interp := New(Options{Stdout: &out, FS: fsys})
+	for i := 0; i < 2; i++ {
+		res, err := interp.RunFile(context.Background(), filepath.Join(dir, "main.bl"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testIntegerObject(t, res, 25)
+	}
+
+	if out.String() != "loaded\n" {
+		t.Errorf("module should be evaluated once. output=%q", out.String())
+	}
+}
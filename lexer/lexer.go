@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/nayyara-airlangga/basedlang/token"
 )
 
@@ -9,10 +11,12 @@ type Lexer struct {
 	position     int  // current position
 	nextPosition int  // position after current
 	ch           byte // current char being read
+	line         int  // line of the current char
+	lineStart    int  // position of the first char of the current line
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readCh()
 	return l
 }
@@ -25,6 +29,11 @@ func (l *Lexer) peekCh() byte {
 }
 
 func (l *Lexer) readCh() {
+	if l.ch == '\n' {
+		l.line++
+		l.lineStart = l.nextPosition
+	}
+
 	if l.nextPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -48,6 +57,27 @@ func (l *Lexer) readString() string {
 	return l.input[pos:l.position]
 }
 
+// readRegex reads the pattern of a regex literal #/.../ with the current char
+// on the #. A slash inside the pattern is written \/. It reports false if the
+// literal isn't terminated.
+func (l *Lexer) readRegex() (string, bool) {
+	l.readCh()
+
+	var pattern []byte
+	for {
+		l.readCh()
+		switch {
+		case l.ch == 0:
+			return string(pattern), false
+		case l.ch == '/':
+			return string(pattern), true
+		case l.ch == '\\' && l.peekCh() == '/':
+			l.readCh()
+		}
+		pattern = append(pattern, l.ch)
+	}
+}
+
 func isLetter(ch byte) bool {
 	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
 }
@@ -67,11 +97,15 @@ func (l *Lexer) readIdent(checkFn func(ch byte) bool) string {
 }
 
 func (l *Lexer) skipWhitespaces() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' || l.ch == '\n' {
+	for isWhitespace(l.ch) {
 		l.readCh()
 	}
 }
 
+func isWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n'
+}
+
 func newToken(tokenType token.TokenType, ch byte) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -93,6 +127,8 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespaces()
 
+	line, column := l.line, l.position-l.lineStart+1
+
 	switch l.ch {
 	case '=':
 		if l.peekCh() == '=' {
@@ -100,6 +136,9 @@ func (l *Lexer) NextToken() token.Token {
 			l.readCh()
 			lit := string(ch) + string(l.ch)
 			tok = newIdentToken(token.EQ, lit)
+		} else if l.peekCh() == '>' {
+			l.readCh()
+			tok = newIdentToken(token.ARROW, "=>")
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -138,8 +177,36 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.GT, l.ch)
 		}
+	case '?':
+		switch {
+		case l.peekCh() == '?':
+			l.readCh()
+			tok = newIdentToken(token.NULLISH, "??")
+		case l.peekCh() == '.':
+			l.readCh()
+			tok = newIdentToken(token.OPTIONAL, "?.")
+		default:
+			tok = newToken(token.QUESTION, l.ch)
+		}
+	case '|':
+		if l.peekCh() == '>' {
+			l.readCh()
+			tok = newIdentToken(token.PIPE, "|>")
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readCh()
+			l.readCh()
+			tok = newIdentToken(token.ELLIPSIS, "...")
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
 	case '(':
@@ -156,22 +223,38 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
 		tok = newStringToken(l.readString())
+	case '#':
+		// Regexes start with a character identifiers can't contain, so that
+		// r/2 stays a division
+		if l.peekCh() == '/' {
+			pattern, terminated := l.readRegex()
+			tok = newIdentToken(token.REGEX, pattern)
+			if !terminated {
+				tok.Type = token.ILLEGAL
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case 0:
 		tok = newEOFToken()
 	default:
 		if isLetter(l.ch) {
 			ident := l.readIdent(isLetter)
 			tok = newIdentToken(token.LookupType(ident), ident)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else if isDigit(l.ch) {
 			num := l.readIdent(isDigit)
 			tok = newIdentToken(token.INT, num)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
 	}
 
+	tok.Line, tok.Column = line, column
+
 	l.readCh()
 	return tok
 }
@@ -31,6 +31,17 @@ if (5 < 10) {
 "foobar";
 "foo bar";
 [1, true, "foo bar"];
+try { throw x; } catch (e) {} finally {}
+x?;
+xs[1:2];
+#/a\/b[0-9]+/; r/2;
+import "lib/x" as x; export let y = 1;
+user.name;
+struct Point { x, y }
+match (xs) { [a, ...b] => a }
+xs |> map(x => x)
+c ? -1 : x?.y ?? z?.[0]; f()? - 1
+const limit = 10;
 `
 
 	expectedTokens := []struct {
@@ -130,6 +141,107 @@ if (5 < 10) {
 		{token.STRING, "foo bar"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
+		{token.TRY, "try"},
+		{token.LBRACE, "{"},
+		{token.THROW, "throw"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.CATCH, "catch"},
+		{token.LPAREN, "("},
+		{token.IDENT, "e"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.FINALLY, "finally"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.QUESTION, "?"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "xs"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.REGEX, "a/b[0-9]+"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "r"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/x"},
+		{token.AS, "as"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "y"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "user"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
+		{token.SEMICOLON, ";"},
+		{token.STRUCT, "struct"},
+		{token.IDENT, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "y"},
+		{token.RBRACE, "}"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "b"},
+		{token.RBRACKET, "]"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "map"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.IDENT, "c"},
+		{token.QUESTION, "?"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.IDENT, "x"},
+		{token.OPTIONAL, "?."},
+		{token.IDENT, "y"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "z"},
+		{token.OPTIONAL, "?."},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.QUESTION, "?"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.CONST, "const"},
+		{token.IDENT, "limit"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SEMICOLON, ";"},
 		{token.EOF, ""},
 	}
 
@@ -147,3 +259,43 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  add(x,
+	"a b");`
+
+	expectedTokens := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"add", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{",", 2, 8},
+		{"a b", 3, 2},
+		{")", 3, 7},
+		{";", 3, 8},
+		{"", 3, 9},
+	}
+
+	l := New(input)
+
+	for i, et := range expectedTokens {
+		tok := l.NextToken()
+
+		if tok.Literal != et.literal {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, et.literal, tok.Literal)
+		}
+		if tok.Line != et.line || tok.Column != et.column {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, et.line, et.column, tok.Line, tok.Column)
+		}
+	}
+}
@@ -1,15 +1,80 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 
+	"github.com/nayyara-airlangga/basedlang/evaluator"
+	"github.com/nayyara-airlangga/basedlang/interpreter"
 	"github.com/nayyara-airlangga/basedlang/repl"
+	"github.com/nayyara-airlangga/basedlang/vfs"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runFile(os.Args[1]))
+	}
+
 	fmt.Printf("Basedlang v0.0.1 on %s %s\n", runtime.GOOS, runtime.GOARCH)
 	fmt.Println("Type away!")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// runFile evaluates the script at path and returns the process exit code.
+func runFile(path string) int {
+	// Scripts may use the files under the directory they are run from
+	fsys, err := vfs.Sandbox(".")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Modules not found next to the importing file are looked up in the
+	// directories listed in BASEDPATH
+	var searchPath []string
+	if dirs := os.Getenv("BASEDPATH"); dirs != "" {
+		searchPath = filepath.SplitList(dirs)
+	}
+
+	// Imports may also come from the script's own directory and the search
+	// path, which the fs builtins can't reach
+	moduleRoots := []string{".", filepath.Dir(path)}
+	for _, dir := range searchPath {
+		if _, err := os.Stat(dir); err == nil {
+			moduleRoots = append(moduleRoots, dir)
+		}
+	}
+	moduleFS, err := vfs.Sandbox(moduleRoots...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	modules := evaluator.NewModuleLoader(searchPath...)
+	modules.ReadFile = moduleFS.ReadFile
+
+	opts := interpreter.Options{FS: fsys, Modules: modules}
+	_, err = interpreter.New(opts).RunFile(context.Background(), path)
+
+	var parseErr *interpreter.ParseError
+	var runtimeErr *interpreter.RuntimeError
+	switch {
+	case errors.As(err, &parseErr):
+		fmt.Fprintln(os.Stderr, "parser errors:")
+		for _, msg := range parseErr.Errors {
+			fmt.Fprintln(os.Stderr, "\t"+msg)
+		}
+	case errors.As(err, &runtimeErr):
+		fmt.Fprintln(os.Stderr, runtimeErr.Err.Inspect())
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+	default:
+		return 0
+	}
+
+	return 1
+}
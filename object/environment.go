@@ -1,8 +1,9 @@
 package object
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool // names declared with const in this scope
+	outer  *Environment
 }
 
 func NewEnvironment() *Environment {
@@ -18,7 +19,7 @@ func NewLocalEnvironment(outer *Environment) *Environment {
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, exists := e.store[name]
 	if !exists && e.outer != nil {
-		obj, exists = e.outer.store[name]
+		obj, exists = e.outer.Get(name)
 	}
 	return obj, exists
 }
@@ -27,3 +28,23 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// SetConst binds name like Set and marks it as a constant of this scope.
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+	return e.Set(name, val)
+}
+
+// Declared reports whether name is bound in this scope, ignoring outer ones.
+func (e *Environment) Declared(name string) bool {
+	_, exists := e.store[name]
+	return exists
+}
+
+// IsConst reports whether name is a constant of this scope.
+func (e *Environment) IsConst(name string) bool {
+	return e.consts[name]
+}
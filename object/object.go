@@ -1,16 +1,25 @@
 package object
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/vfs"
 )
 
 type ObjectType string
 
 const (
 	ERROR        ObjectType = "ERROR"
+	EXCEPTION    ObjectType = "EXCEPTION"
 	INTEGER      ObjectType = "INTEGER"
 	BOOLEAN      ObjectType = "BOOLEAN"
 	STRING       ObjectType = "STRING"
@@ -19,6 +28,11 @@ const (
 	FUNCTION     ObjectType = "FUNCTION"
 	ARRAY        ObjectType = "ARRAY"
 	BUILTIN      ObjectType = "BUILTIN"
+	HASH         ObjectType = "HASH"
+	REGEX        ObjectType = "REGEX"
+	MODULE       ObjectType = "MODULE"
+	STRUCT_TYPE  ObjectType = "STRUCT_TYPE"
+	STRUCT       ObjectType = "STRUCT"
 )
 
 type Object interface {
@@ -26,12 +40,111 @@ type Object interface {
 	Inspect() string
 }
 
+type ErrorKind string
+
+const (
+	// Raised by throw with a value that is not an error
+	USER_ERROR ErrorKind = "Error"
+
+	// Raised by the evaluator and builtins
+	TYPE_ERROR           ErrorKind = "TypeError"
+	INDEX_ERROR          ErrorKind = "IndexError"
+	NAME_ERROR           ErrorKind = "NameError"
+	ARGUMENT_ERROR       ErrorKind = "ArgumentError"
+	STACK_OVERFLOW_ERROR ErrorKind = "StackOverflowError"
+	HOST_ERROR           ErrorKind = "HostError"
+	PERMISSION_ERROR     ErrorKind = "PermissionError"
+	IMPORT_ERROR         ErrorKind = "ImportError"
+	MATCH_ERROR          ErrorKind = "MatchError"
+	ZERO_DIVISION_ERROR  ErrorKind = "ZeroDivisionError"
+
+	// Raised when an evaluation is stopped from the outside. These can't be
+	// caught.
+	CANCELED_ERROR ErrorKind = "CanceledError"
+	TIMEOUT_ERROR  ErrorKind = "TimeoutError"
+	BUDGET_ERROR   ErrorKind = "BudgetError"
+)
+
+// Catchable reports whether a try expression may handle errors of kind k.
+func (k ErrorKind) Catchable() bool {
+	return k != CANCELED_ERROR && k != TIMEOUT_ERROR && k != BUDGET_ERROR
+}
+
+// tracebackEdge is how many frames Error.Inspect prints from each end of a
+// long stack.
+const tracebackEdge = 10
+
+// Frame is one function call an error propagated through on its way up.
+type Frame struct {
+	Function string // name the function was bound to with let, if any
+	Args     string // summary of the arguments the function was called with
+	Line     int    // position of the call site
+	Column   int
+}
+
+func (f Frame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("%s(%s) at line %d, column %d", name, f.Args, f.Line, f.Column)
+}
+
 type Error struct {
+	Kind    ErrorKind
 	Message string
+	Frames  []Frame // innermost call first
+	Cause   *Error  // error that led to this one, if any
 }
 
 func (e *Error) Type() ObjectType { return ERROR }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+
+// Error lets errors travel through Go code that expects an error.
+func (e *Error) Error() string {
+	if e.Kind != "" {
+		return string(e.Kind) + ": " + e.Message
+	}
+	return e.Message
+}
+
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("ERROR: ")
+	if e.Kind != "" {
+		out.WriteString(string(e.Kind) + ": ")
+	}
+	out.WriteString(e.Message)
+
+	if len(e.Frames) > 0 {
+		out.WriteString("\nTraceback (most recent call last):")
+		for i := len(e.Frames) - 1; i >= 0; i-- {
+			// Deep recursion is shortened to both ends of the stack
+			if omitted := len(e.Frames) - 2*tracebackEdge; omitted > 0 && i == len(e.Frames)-tracebackEdge-1 {
+				out.WriteString(fmt.Sprintf("\n  ... %d more frames", omitted))
+				i -= omitted - 1
+				continue
+			}
+			out.WriteString("\n  " + e.Frames[i].String())
+		}
+	}
+
+	if e.Cause != nil {
+		out.WriteString("\nCaused by: ")
+		out.WriteString(strings.TrimPrefix(e.Cause.Inspect(), "ERROR: "))
+	}
+
+	return out.String()
+}
+
+// Exception wraps an error while it unwinds the stack, the same way
+// ReturnValue wraps a returned value.
+type Exception struct {
+	Err *Error
+}
+
+func (e *Exception) Type() ObjectType { return EXCEPTION }
+func (e *Exception) Inspect() string  { return e.Err.Inspect() }
 
 type Integer struct {
 	Value int64
@@ -54,6 +167,76 @@ type String struct {
 func (s *String) Type() ObjectType { return STRING }
 func (s *String) Inspect() string  { return s.Value }
 
+// Regex is a compiled regular expression.
+type Regex struct {
+	Regexp *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType { return REGEX }
+func (r *Regex) Inspect() string {
+	return "#/" + strings.ReplaceAll(r.Regexp.String(), "/", `\/`) + "/"
+}
+
+// Module is an imported file. Only the bindings it exports are visible to
+// importers.
+type Module struct {
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE }
+func (m *Module) Inspect() string  { return "<module " + m.Path + ">" }
+
+// StructType is a struct declaration. Calling it constructs an instance.
+type StructType struct {
+	Name   string
+	Fields []string
+}
+
+func (st *StructType) Type() ObjectType { return STRUCT_TYPE }
+func (st *StructType) Inspect() string  { return "<struct " + st.Name + ">" }
+
+// FieldIndex returns the position of field in the struct's fields, or -1.
+func (st *StructType) FieldIndex(field string) int {
+	for i, f := range st.Fields {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// Struct is an instance of a StructType, holding one value per field.
+type Struct struct {
+	StructType *StructType
+	Values     []Object
+	Frozen     bool // set by freeze, fields can't be assigned to
+}
+
+func (s *Struct) Type() ObjectType { return STRUCT }
+func (s *Struct) Inspect() string  { return s.inspect(map[Object]bool{}) }
+
+func (s *Struct) inspect(seen map[Object]bool) string {
+	if seen[s] {
+		return s.StructType.Name + "{...}"
+	}
+	seen[s] = true
+	defer delete(seen, s)
+
+	var out bytes.Buffer
+
+	out.WriteString(s.StructType.Name + "{")
+	for i, field := range s.StructType.Fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(field + ": " + inspect(s.Values[i], seen))
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL }
@@ -67,44 +250,69 @@ func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
 type Function struct {
-	Params []*ast.Identifier
+	Name   string // from fn name(...) or the first let statement binding it
+	Params []*ast.Parameter
+	Rest   ast.Pattern
 	Body   *ast.BlockStatement
 	Env    *Environment
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION }
+
+// Inspect prints the function's name and arity, like <fn fib/1>. Optional
+// parameters show as a range, <fn f/1-2>, and a rest parameter as <fn f/1+>.
 func (f *Function) Inspect() string {
-	var out bytes.Buffer
+	min, max := f.Arity()
+
+	arity := strconv.Itoa(min)
+	switch {
+	case max < 0:
+		arity += "+"
+	case max > min:
+		arity += "-" + strconv.Itoa(max)
+	}
 
-	out.WriteString("fn")
-	out.WriteString("(")
+	if f.Name == "" {
+		return "<fn/" + arity + ">"
+	}
+	return "<fn " + f.Name + "/" + arity + ">"
+}
 
-	for i, p := range f.Params {
-		out.WriteString(p.String())
-		if i+1 != len(f.Params) {
-			out.WriteString(", ")
+// Arity returns how many arguments f accepts. max is -1 when f has a rest
+// parameter.
+func (f *Function) Arity() (min, max int) {
+	for _, p := range f.Params {
+		if p.Default == nil {
+			min++
 		}
 	}
-
-	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
-
-	return out.String()
+	if f.Rest != nil {
+		return min, -1
+	}
+	return min, len(f.Params)
 }
 
 type Array struct {
-	Elems []Object
+	Elems  []Object
+	Frozen bool // set by freeze, elements can't be assigned to
 }
 
 func (a *Array) Type() ObjectType { return ARRAY }
-func (a *Array) Inspect() string {
+func (a *Array) Inspect() string  { return a.inspect(map[Object]bool{}) }
+
+func (a *Array) inspect(seen map[Object]bool) string {
+	if seen[a] {
+		return "[...]"
+	}
+	seen[a] = true
+	defer delete(seen, a)
+
 	var out bytes.Buffer
 
 	out.WriteString("[")
 
 	for i, e := range a.Elems {
-		out.WriteString(e.Inspect())
+		out.WriteString(inspect(e, seen))
 		if i+1 != len(a.Elems) {
 			out.WriteString(", ")
 		}
@@ -115,7 +323,131 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
-type BuiltinFn func(args ...Object) Object
+// HashKey identifies a value used as a key in a Hash.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values that can be used as hash keys.
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Hashable
+	Value Object
+}
+
+// Hash maps keys to values and remembers the order keys were first added in.
+type Hash struct {
+	Pairs  map[HashKey]HashPair
+	Keys   []HashKey // insertion order
+	Frozen bool      // set by freeze, keys can't be assigned to
+}
+
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	pair, exists := h.Pairs[key.HashKey()]
+	return pair.Value, exists
+}
+
+func (h *Hash) Set(key Hashable, val Object) {
+	hk := key.HashKey()
+	if _, exists := h.Pairs[hk]; !exists {
+		h.Keys = append(h.Keys, hk)
+	}
+	h.Pairs[hk] = HashPair{Key: key, Value: val}
+}
+
+func (h *Hash) Type() ObjectType { return HASH }
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+
+func (h *Hash) inspect(seen map[Object]bool) string {
+	if seen[h] {
+		return "{...}"
+	}
+	seen[h] = true
+	defer delete(seen, h)
+
+	var out bytes.Buffer
+
+	out.WriteString("{")
+
+	for i, hk := range h.Keys {
+		pair := h.Pairs[hk]
+		out.WriteString(pair.Key.Inspect())
+		out.WriteString(": ")
+		out.WriteString(inspect(pair.Value, seen))
+		if i+1 != len(h.Keys) {
+			out.WriteString(", ")
+		}
+	}
+
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// inspect prints o as part of a container. seen holds the containers being
+// printed, so that one that contains itself prints as [...], {...} or
+// Name{...} instead of recursing forever.
+func inspect(o Object, seen map[Object]bool) string {
+	switch o := o.(type) {
+	case *Array:
+		return o.inspect(seen)
+	case *Hash:
+		return o.inspect(seen)
+	case *Struct:
+		return o.inspect(seen)
+	default:
+		return o.Inspect()
+	}
+}
+
+// CallContext gives a builtin access to the evaluation that called it.
+type CallContext interface {
+	Context() context.Context
+	// Env is the environment of the call site.
+	Env() *Environment
+	// Out is where the evaluation writes its output.
+	Out() io.Writer
+	// ErrOut is where the evaluation writes diagnostics.
+	ErrOut() io.Writer
+	// In is where the evaluation reads its input from. It is shared by every
+	// builtin so that buffered input isn't lost between calls.
+	In() *bufio.Reader
+	// FS is the filesystem the fs builtins work on. It is nil when the host
+	// didn't grant filesystem access.
+	FS() vfs.FS
+	// Call calls a basedlang function or builtin. A thrown error comes back
+	// as an EXCEPTION object that the builtin should return unchanged.
+	Call(fn Object, args ...Object) Object
+}
+
+type BuiltinFn func(c CallContext, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFn
@@ -2,7 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"path"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/nayyara-airlangga/basedlang/ast"
 	"github.com/nayyara-airlangga/basedlang/lexer"
@@ -20,13 +23,18 @@ type precedence int
 const (
 	_ precedence = iota
 	LOWEST
+	ASSIGN      // obj.field = X
+	TERNARY     // cond ? X : Y
+	NULLISH     // X ?? Y
 	EQUALS      // ==
 	LESSGREATER // > or <
+	PIPE        // xs |> f()
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
-	INDEX       // arr[1]
+	INDEX       // arr[1] or obj.field
+	POSTFIX     // X?
 )
 
 type Parser struct {
@@ -38,6 +46,19 @@ type Parser struct {
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
 
+	// noArrow is set while parsing a match guard, where => ends the guard
+	// instead of starting an arrow function. Brackets inside the guard allow
+	// arrow functions again.
+	noArrow bool
+
+	// conditionals remembers, by position, which ? tokens start the
+	// consequence of cond ? a : b rather than being the postfix operator
+	conditionals map[[2]int]conditional
+
+	// lastGroup is the expression most recently parsed inside parentheses,
+	// so |> can tell (f(1)) from a bare f(1)
+	lastGroup ast.Expression
+
 	errors []string
 }
 
@@ -52,26 +73,97 @@ func (p *Parser) registerInfix(t token.TokenType, fn infixParseFn) {
 func (p *Parser) nextToken() {
 	p.curTok = p.peekTok
 	p.peekTok = p.l.NextToken()
+
+	if p.peekTok.Type == token.QUESTION && p.isConditional() {
+		p.peekTok.Type = token.TERNARY
+	}
 }
 
-func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+// isConditional reports whether the ? in peekTok starts the consequence of
+// cond ? a : b rather than being the postfix operator, so that g()? on the end
+// of a line stays the postfix operator whatever the next line starts with.
+func (p *Parser) isConditional() bool {
+	return p.scanConditional(p.peekTok, *p.l).isConditional
+}
 
-	// Set curTok and peekTok
-	p.nextToken()
-	p.nextToken()
+// conditional is what scanConditional found out about a ? token.
+type conditional struct {
+	isConditional bool
+	hasColon      bool
+	afterColon    lexer.Lexer // positioned after the colon, if hasColon
+}
+
+// scanConditional looks ahead from the ? token q, with l positioned after it,
+// for the colon that would make it a conditional. The colon has to be outside
+// any brackets and before the statement ends. Inner conditionals are skipped
+// along with their own colon, and every result is remembered, so that nested
+// conditionals are only scanned once. A ? followed on its line by something
+// that can only start an operand is a conditional even without a colon, so
+// that a ? b reports the missing colon.
+func (p *Parser) scanConditional(q token.Token, l lexer.Lexer) conditional {
+	pos := [2]int{q.Line, q.Column}
+	if c, known := p.conditionals[pos]; known {
+		return c
+	}
+
+	var c conditional
+
+	first := l.NextToken()
+	if p.prefixParseFns[first.Type] != nil {
+		c.isConditional = first.Line == q.Line && p.infixParseFns[first.Type] == nil
+
+		depth := 0
+	scan:
+		for tok := first; ; tok = l.NextToken() {
+			switch tok.Type {
+			case token.LPAREN, token.LBRACKET, token.LBRACE:
+				depth++
+			case token.RPAREN, token.RBRACKET, token.RBRACE:
+				depth--
+				if depth < 0 {
+					break scan
+				}
+			case token.SEMICOLON, token.LET, token.CONST, token.RETURN, token.THROW,
+				token.IMPORT, token.EXPORT, token.STRUCT:
+				if depth == 0 {
+					break scan
+				}
+			case token.EOF:
+				break scan
+			case token.QUESTION:
+				if inner := p.scanConditional(tok, l); inner.hasColon {
+					l = inner.afterColon
+				}
+			case token.COLON:
+				if depth == 0 {
+					c = conditional{isConditional: true, hasColon: true, afterColon: l}
+					break scan
+				}
+			}
+		}
+	}
+
+	p.conditionals[pos] = c
+	return c
+}
+
+func New(l *lexer.Lexer) *Parser {
+	p := &Parser{l: l, errors: []string{}, conditionals: make(map[[2]int]conditional)}
 
 	// Register prefix functions
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntLiteral)
 	p.registerPrefix(token.STRING, p.parseString)
+	p.registerPrefix(token.REGEX, p.parseRegex)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 
@@ -89,6 +181,17 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.QUESTION, p.parsePostfixExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.TERNARY, p.parseConditionalExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.OPTIONAL, p.parseOptionalChain)
+
+	// Set curTok and peekTok
+	p.nextToken()
+	p.nextToken()
 
 	return p
 }
@@ -111,10 +214,23 @@ func (p *Parser) Parse() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curTok.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.FUNCTION:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -123,13 +239,18 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curTok}
 
-	// Expects an identifier after the let keyword
-	if !p.expectPeek(token.IDENT) {
+	// Expects an identifier or a destructuring pattern after the let keyword
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+			return nil
+		}
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
+	} else {
+		stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
-
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -159,6 +280,140 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curTok}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseImportStatement parses import "path" as alias. Without an alias the
+// module is bound to the last element of its path.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curTok}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = p.curTok.Literal
+
+	if p.peekTokenIs(token.AS) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	} else {
+		name := strings.TrimSuffix(path.Base(stmt.Path), path.Ext(stmt.Path))
+		if token.LookupType(name) != token.IDENT || strings.TrimFunc(name, isIdentRune) != "" {
+			msg := fmt.Sprintf("import of %q needs an alias", stmt.Path)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		stmt.Alias = &ast.Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func isIdentRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_'
+}
+
+// parseFunctionStatement parses fn name(params) { body } at the start of a
+// statement.
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	f, isFunc := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+	if !isFunc || f == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return &ast.FunctionStatement{Function: f}
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curTok}
+
+	if p.peekTokenIs(token.FUNCTION) {
+		p.nextToken()
+		if !p.peekTokenIs(token.IDENT) {
+			p.peekErr(token.IDENT)
+			return nil
+		}
+		if stmt.Fn = p.parseFunctionStatement(); stmt.Fn == nil {
+			return nil
+		}
+		return stmt
+	}
+
+	if !p.peekTokenIs(token.CONST) && !p.expectPeek(token.LET) {
+		return nil
+	}
+	if p.peekTokenIs(token.CONST) {
+		p.nextToken()
+	}
+	if stmt.Let = p.parseLetStatement(); stmt.Let == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+// parseStructStatement parses struct Name { field, ... }.
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curTok}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+		if seen[field.Value] {
+			msg := fmt.Sprintf("duplicate field %s in struct %s", field.Value, stmt.Name.Value)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curTok}
 
@@ -196,7 +451,14 @@ func (p *Parser) parseExpression(pr precedence) ast.Expression {
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
-	return &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	ident := &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+
+	if p.peekTokenIs(token.ARROW) && !p.noArrow {
+		p.nextToken()
+		return p.parseArrowFunction(ident.Token, []ast.Expression{ident})
+	}
+
+	return ident
 }
 
 func (p *Parser) parseIntLiteral() ast.Expression {
@@ -218,20 +480,127 @@ func (p *Parser) parseString() ast.Expression {
 	return &ast.StringLiteral{Token: p.curTok, Value: p.curTok.Literal}
 }
 
+func (p *Parser) parseRegex() ast.Expression {
+	re, err := regexp.Compile(p.curTok.Literal)
+	if err != nil {
+		msg := fmt.Sprintf("could not parse #/%s/ as regex: %s", p.curTok.Literal, err)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return &ast.RegexLiteral{Token: p.curTok, Regexp: re}
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.BooleanLiteral{Token: p.curTok, Value: p.curTokenIs(token.TRUE)}
 }
 
+// parseGroupedExpression parses (expr), or the parameter list of an arrow
+// function like (a, b) => a + b.
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	lparen := p.curTok
+	arrowOK := !p.noArrow
+	defer p.allowArrows()()
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		if !arrowOK || !p.expectPeek(token.ARROW) {
+			p.noPrefixParseFnErr(token.RPAREN)
+			return nil
+		}
+		return p.parseArrowFunction(lparen, nil)
+	}
+
 	p.nextToken()
 
-	expr := p.parseExpression(LOWEST)
+	exprs := []ast.Expression{p.parseExpression(LOWEST)}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		exprs = append(exprs, p.parseExpression(LOWEST))
+	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return expr
+	if arrowOK && p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		return p.parseArrowFunction(lparen, exprs)
+	}
+	if len(exprs) > 1 {
+		p.peekErr(token.ARROW)
+		return nil
+	}
+
+	p.lastGroup = exprs[0]
+	return exprs[0]
+}
+
+// allowArrows turns arrow functions back on inside brackets and returns a
+// func that restores the previous setting.
+func (p *Parser) allowArrows() func() {
+	noArrow := p.noArrow
+	p.noArrow = false
+	return func() { p.noArrow = noArrow }
+}
+
+// parseArrowFunction parses the body of x => body or (a, b) => body, where
+// params are the expressions before the arrow, and desugars it into a
+// function literal. The current token is =>. A body that isn't a block is a
+// single expression.
+func (p *Parser) parseArrowFunction(start token.Token, params []ast.Expression) ast.Expression {
+	f := &ast.FunctionLiteral{
+		Token:  token.Token{Type: token.FUNCTION, Literal: "fn", Line: start.Line, Column: start.Column},
+		Params: []*ast.Parameter{},
+	}
+
+	for _, param := range params {
+		ident, isIdent := param.(*ast.Identifier)
+		if !isIdent {
+			msg := fmt.Sprintf("invalid arrow function parameter %s", param)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		var pattern ast.Pattern = &ast.BindingPattern{Name: ident}
+		if ident.Value == "_" {
+			pattern = &ast.WildcardPattern{Token: ident.Token}
+		}
+		f.Params = append(f.Params, &ast.Parameter{Pattern: pattern})
+	}
+
+	p.nextToken()
+
+	if p.curTokenIs(token.LBRACE) {
+		f.Body = p.parseBlockStatement()
+		return f
+	}
+
+	stmt := &ast.ExpressionStatement{Token: p.curTok, Expression: p.parseExpression(LOWEST)}
+	f.Body = &ast.BlockStatement{Token: p.curTok, Statements: []ast.Statement{stmt}}
+
+	return f
+}
+
+// parsePipeExpression parses left |> right. A bare call on the right gets left
+// as its first argument, anything else, including a call in parentheses, is
+// called with left as its only argument.
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	tok := p.curTok
+
+	p.nextToken()
+
+	right := p.parseExpression(PIPE)
+	if right == nil {
+		return nil
+	}
+
+	if call, isCall := right.(*ast.CallExpression); isCall && right != p.lastGroup {
+		call.Args = append([]ast.Expression{left}, call.Args...)
+		return call
+	}
+
+	return &ast.CallExpression{Token: tok, Function: right, Args: []ast.Expression{left}}
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -270,8 +639,59 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expr
 }
 
+func (p *Parser) parseTryExpression() ast.Expression {
+	expr := &ast.TryExpression{Token: p.curTok}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expr.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		expr.CatchParam = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expr.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expr.Finally = p.parseBlockStatement()
+	}
+
+	if expr.Catch == nil && expr.Finally == nil {
+		msg := "expected catch or finally after try block"
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return expr
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	b := &ast.BlockStatement{Token: p.curTok, Statements: []ast.Statement{}}
+	defer p.allowArrows()()
 
 	p.nextToken()
 
@@ -289,11 +709,16 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	f := &ast.FunctionLiteral{Token: p.curTok}
 
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		f.Name = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+	}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	f.Params = p.parseFunctionParameters()
+	f.Params, f.Rest = p.parseFunctionParameters()
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -340,17 +765,29 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	a := &ast.ArrayLiteral{Token: p.curTok}
+	defer p.allowArrows()()
 	a.Elems = p.parseExpressionList(token.RBRACKET)
 	return a
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	idx := &ast.IndexExpression{Token: p.curTok, Left: left}
+	tok := p.curTok
+	defer p.allowArrows()()
 
 	p.nextToken()
 
+	if p.curTokenIs(token.COLON) {
+		return p.parseSliceExpression(tok, left, nil)
+	}
+
+	idx := &ast.IndexExpression{Token: tok, Left: left}
 	idx.Index = p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(tok, left, idx.Index)
+	}
+
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -358,32 +795,206 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	return idx
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	params := []*ast.Identifier{}
+func (p *Parser) parseMemberExpression(obj ast.Expression) ast.Expression {
+	member := &ast.MemberExpression{Token: p.curTok, Object: obj}
 
-	if p.peekTokenIs(token.RPAREN) {
-		return params
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	member.Property = &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}
+
+	return member
+}
+
+// parseOptionalChain parses obj?.property and obj?.[index], which give null
+// instead of failing when obj is null.
+func (p *Parser) parseOptionalChain(obj ast.Expression) ast.Expression {
+	if !p.peekTokenIs(token.LBRACKET) {
+		member, isMember := p.parseMemberExpression(obj).(*ast.MemberExpression)
+		if !isMember {
+			return nil
+		}
+		member.Optional = true
+		return member
+	}
+
+	p.nextToken()
+
+	switch expr := p.parseIndexExpression(obj).(type) {
+	case *ast.IndexExpression:
+		expr.Optional = true
+		return expr
+	case *ast.SliceExpression:
+		expr.Optional = true
+		return expr
+	default:
+		return nil
 	}
+}
+
+// parseConditionalExpression parses the rest of cond ? consequence :
+// alternative. It is right associative, so a ? b : c ? d : e nests in the
+// alternative.
+func (p *Parser) parseConditionalExpression(cond ast.Expression) ast.Expression {
+	expr := &ast.ConditionalExpression{Token: p.curTok, Condition: cond}
 
 	p.nextToken()
+	expr.Consequence = p.parseExpression(LOWEST)
 
-	params = append(params, &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal})
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
 
-	for p.peekTokenIs(token.COMMA) {
+	p.nextToken()
+	expr.Alternative = p.parseExpression(TERNARY - 1)
+
+	return expr
+}
+
+// parseSliceExpression parses the rest of xs[start:end:step] with the current
+// token on the first colon.
+func (p *Parser) parseSliceExpression(tok token.Token, left, start ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	slice.End = p.parseSlicePart()
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		slice.Step = p.parseSlicePart()
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return slice
+}
+
+// parseSlicePart parses the expression after a colon in a slice, if there is
+// one.
+func (p *Parser) parseSlicePart() ast.Expression {
+	if p.peekTokenIs(token.COLON) || p.peekTokenIs(token.RBRACKET) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(LOWEST)
+}
+
+// parseFunctionParameters parses the parameter list, where each parameter is
+// a name or a destructuring pattern with an optional default, optionally
+// followed by a ...rest parameter.
+func (p *Parser) parseFunctionParameters() ([]*ast.Parameter, ast.Pattern) {
+	params := []*ast.Parameter{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		return params, nil
+	}
+
+	for {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil, nil
+			}
+			rest := p.parsePattern()
+			if !p.peekTokenIs(token.RPAREN) {
+				p.errors = append(p.errors, "rest parameter must be the last parameter")
+				return nil, nil
+			}
+			return params, rest
+		}
+
+		param := p.parseParameter()
+		if param == nil {
+			return nil, nil
+		}
+		if param.Default == nil && len(params) > 0 && params[len(params)-1].Default != nil {
+			msg := fmt.Sprintf("parameter %s without a default follows a parameter with one", param.Pattern.String())
+			p.errors = append(p.errors, msg)
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			return params, nil
+		}
+		p.nextToken()
+	}
+}
+
+func (p *Parser) parseParameter() *ast.Parameter {
+	switch p.curTok.Type {
+	case token.IDENT, token.LBRACKET, token.LBRACE:
+	default:
+		msg := fmt.Sprintf("invalid parameter %s", p.curTok.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	param := &ast.Parameter{Pattern: p.parsePattern()}
+	if param.Pattern == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
 		p.nextToken()
 		p.nextToken()
-		params = append(params, &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal})
+		param.Default = p.parseExpression(LOWEST)
 	}
 
-	return params
+	return param
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	call := &ast.CallExpression{Token: p.curTok, Function: function}
-	call.Args = p.parseExpressionList(token.RPAREN)
+	call.Args = p.parseCallArguments()
 	return call
 }
 
+// parseCallArguments parses the arguments of a call, which may spread arrays
+// with ...xs and name parameters with name: value. Named arguments come after
+// all positional ones.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+	named := false
+	defer p.allowArrows()()
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		var arg ast.Expression
+		switch {
+		case p.curTokenIs(token.ELLIPSIS):
+			spread := &ast.SpreadExpression{Token: p.curTok}
+			p.nextToken()
+			spread.Value = p.parseExpression(LOWEST)
+			arg = spread
+		case p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON):
+			na := &ast.NamedArgument{Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+			p.nextToken()
+			p.nextToken()
+			na.Value = p.parseExpression(LOWEST)
+			arg = na
+		default:
+			arg = p.parseExpression(LOWEST)
+		}
+
+		if _, isNamed := arg.(*ast.NamedArgument); isNamed {
+			named = true
+		} else if named && arg != nil {
+			p.errors = append(p.errors, fmt.Sprintf("argument %s follows a named argument", arg))
+		}
+		args = append(args, arg)
+
+		if !p.peekTokenIs(token.RPAREN) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return args
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expr := &ast.PrefixExpression{Token: p.curTok, Operator: p.curTok.Literal}
 
@@ -405,6 +1016,41 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expr
 }
 
+// parseAssignExpression parses the right side of an assignment, which is right
+// associative.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expr := &ast.AssignExpression{Token: p.curTok, Target: target}
+
+	if !isAssignable(target) {
+		msg := fmt.Sprintf("cannot assign to %s", target.String())
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	p.nextToken()
+
+	expr.Value = p.parseExpression(LOWEST)
+
+	return expr
+}
+
+// isAssignable reports whether target is a member or an index, which are the
+// only things that can be assigned to. Optional chains can't be.
+func isAssignable(target ast.Expression) bool {
+	switch target := target.(type) {
+	case *ast.MemberExpression:
+		return !target.Optional
+	case *ast.IndexExpression:
+		return !target.Optional
+	default:
+		return false
+	}
+}
+
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	return &ast.PostfixExpression{Token: p.curTok, Left: left, Operator: p.curTok.Literal}
+}
+
 func (p *Parser) noPrefixParseFnErr(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function found for %s", t)
 	p.errors = append(p.errors, msg)
@@ -412,18 +1058,28 @@ func (p *Parser) noPrefixParseFnErr(t token.TokenType) {
 
 func getPrecedence(t token.TokenType) precedence {
 	switch t {
+	case token.ASSIGN:
+		return ASSIGN
+	case token.TERNARY:
+		return TERNARY
+	case token.NULLISH:
+		return NULLISH
 	case token.EQ, token.NEQ:
 		return EQUALS
 	case token.LT, token.GT, token.LTE, token.GTE:
 		return LESSGREATER
+	case token.PIPE:
+		return PIPE
 	case token.PLUS, token.MINUS:
 		return SUM
 	case token.ASTERISK, token.SLASH:
 		return PRODUCT
 	case token.LPAREN:
 		return CALL
-	case token.LBRACKET:
+	case token.LBRACKET, token.DOT, token.OPTIONAL:
 		return INDEX
+	case token.QUESTION:
+		return POSTFIX
 	default:
 		return LOWEST
 	}
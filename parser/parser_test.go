@@ -106,6 +106,36 @@ func TestStringExpression(t *testing.T) {
 	}
 }
 
+func TestRegexExpression(t *testing.T) {
+	p := New(lexer.New(`#/^(\w+)\/(\d+)$/`))
+	program := p.Parse()
+
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	regex, isRegex := stmt.Expression.(*ast.RegexLiteral)
+	if !isRegex {
+		t.Fatalf("stmt.Expression is not *ast.RegexLiteral. got=%T", stmt.Expression)
+	}
+	if regex.Regexp.String() != `^(\w+)/(\d+)$` {
+		t.Errorf("regex.Regexp is not %q. got=%q", `^(\w+)/(\d+)$`, regex.Regexp.String())
+	}
+	if regex.String() != `#/^(\w+)\/(\d+)$/` {
+		t.Errorf("regex.String() wrong. got=%q", regex.String())
+	}
+}
+
+func TestInvalidRegex(t *testing.T) {
+	for _, input := range []string{"#/a(/", "#/abc", "#a"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
 func TestArrayExpression(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -146,6 +176,35 @@ func TestIndexExpression(t *testing.T) {
 	testInfixExpression(t, idxExpr.Index, 1, "+", 1)
 }
 
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs[1:2]", "(xs[1:2])"},
+		{"xs[1:]", "(xs[1:])"},
+		{"xs[:-1]", "(xs[:(-1)])"},
+		{"xs[:]", "(xs[:])"},
+		{"xs[::2]", "(xs[::2])"},
+		{"xs[a + 1:b:-1]", "(xs[(a + 1):b:(-1)])"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, isSlice := stmt.Expression.(*ast.SliceExpression); !isSlice {
+			t.Errorf("stmt.Expression is not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
 func TestIfExpression(t *testing.T) {
 	input := `
 	if (x < y) {
@@ -370,8 +429,8 @@ func TestFunctionLiteral(t *testing.T) {
 		t.Fatalf("Unexpected number of parameters. expected=%d, got=%d", 2, len(function.Params))
 	}
 
-	testLiteralExpression(t, function.Params[0], "x")
-	testLiteralExpression(t, function.Params[1], "y")
+	testBindingPattern(t, function.Params[0].Pattern, "x")
+	testBindingPattern(t, function.Params[1].Pattern, "y")
 
 	if len(function.Body.Statements) != 1 {
 		t.Fatalf("Unexpected number of statements in Body.Statements. expected=%d, got=%d", 1, len(function.Body.Statements))
@@ -409,7 +468,247 @@ func TestFunctionParams(t *testing.T) {
 		}
 
 		for i, param := range tc.expected {
-			testLiteralExpression(t, function.Params[i], param)
+			testBindingPattern(t, function.Params[i].Pattern, param)
+		}
+	}
+}
+
+func TestDefaultRestAndSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 10) { x + y }", "fn(x, y = 10) (x + y)"},
+		{"fn(first, ...others) { others }", "fn(first, ...others) others"},
+		{"fn(...all) { all }", "fn(...all) all"},
+		{"fn([a, b] = [1, 2], c = a * 2) { c }", "fn([a, b] = [1, 2], c = (a * 2)) c"},
+		{"f(...xs)", "f(...xs)"},
+		{"f(1, ...xs, 2)", "f(1, ...xs, 2)"},
+		{"f(1, y: 2 + 3, z: 4,)", "f(1, y: (2 + 3), z: 4)"},
+		{"f()", "f()"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{
+		"fn(x = 1, y) { x }",
+		"fn(...rest, x) { x }",
+		"fn(...[a]) { a }",
+		"fn(x = ) { x }",
+		"f(y: 1, 2)",
+		"f(1 2)",
+	} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestFunctionStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		isStmt   bool
+	}{
+		{"fn fib(n) { fib(n - 1) }", "fn fib(n) fib((n - 1))", true},
+		{"fn noop() {};", "fn noop() ", true},
+		{"export fn twice(x) { x * 2 }", "export fn twice(x) (x * 2)", true},
+		{"let f = fn inner(x) { x };", "let f = fn inner(x) x;", false},
+		{"fn(x) { x }(1)", "fn(x) x(1)", false},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		_, isExprStmt := program.Statements[0].(*ast.ExpressionStatement)
+		_, isLetStmt := program.Statements[0].(*ast.LetStatement)
+		if isStmt := !isExprStmt && !isLetStmt; isStmt != tc.isStmt {
+			t.Errorf("wrong statement type for %s. got=%T", tc.input, program.Statements[0])
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{"fn f { 1 }", "export fn(x) { x }", "fn 1() { 1 }"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestArrowFunctions(t *testing.T) {
+	tests := []struct {
+		input  string
+		params []string
+		body   string
+	}{
+		{"x => x * 2", []string{"x"}, "(x * 2)"},
+		{"(a, b) => a + b", []string{"a", "b"}, "(a + b)"},
+		{"(x) => x", []string{"x"}, "x"},
+		{"() => 1", []string{}, "1"},
+		{"_ => 0", []string{"_"}, "0"},
+		{"x => { let y = x; y }", []string{"x"}, "let y = x;y"},
+		{"x => y => x + y", []string{"x"}, "fn(y) (x + y)"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, isFunction := stmt.Expression.(*ast.FunctionLiteral)
+		if !isFunction {
+			t.Fatalf("stmt.Expression is not *ast.FunctionLiteral. got=%T", stmt.Expression)
+		}
+		if len(function.Params) != len(tc.params) {
+			t.Fatalf("wrong number of parameters for %s. expected=%d, got=%d", tc.input, len(tc.params), len(function.Params))
+		}
+		for i, param := range tc.params {
+			if function.Params[i].Pattern.String() != param {
+				t.Errorf("wrong parameter %d for %s. expected=%q, got=%q", i, tc.input, param, function.Params[i].Pattern)
+			}
+		}
+		if function.Body.String() != tc.body {
+			t.Errorf("wrong body for %s. expected=%q, got=%q", tc.input, tc.body, function.Body)
+		}
+	}
+
+	for _, input := range []string{"(1, b) => b", "(a, b)", "()", "x =>", "xs |>"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestConditionalAndNullOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"c ? a : b", "(c ? a : b)"},
+		{"xs?.[1:]", "(xs?.[1:])"},
+		{"f(x)?\nlet y = 1", "(f(x)?)let y = 1;"},
+		{"fn() {\n  g()?\n  x + 1\n}", "fn() (g()?)(x + 1)"},
+		{"g()?\nc ? 1 : 2", "(g()?)(c ? 1 : 2)"},
+		{"c ? g()? : h()?", "(c ? (g()?) : (h()?))"},
+		{"a + b ? c : d", "((a + b) ? c : d)"},
+		{"a ? b ? c : d : e ? f : g", "(a ? (b ? c : d) : (e ? f : g))"},
+		{"x? - 1", "((x?) - 1)"},
+		{"c ? -1 : 1", "(c ? (-1) : 1)"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{"a ? b", "a ? b c", "a ? b :", "a ? : b", "x?.1", "x?.b = 1"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestConstStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const limit = 10", "const limit = 10;"},
+		{"const [a, b] = xs;", "const [a, b] = xs;"},
+		{"export const x = 1", "export const x = 1;"},
+		{"xs[0] = h.k = 1", "((xs[0]) = ((h.k) = 1))"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	stmt := New(lexer.New("const x = 1")).Parse().Statements[0].(*ast.LetStatement)
+	if !stmt.IsConst() {
+		t.Errorf("stmt.IsConst() is false for const x = 1")
+	}
+}
+
+func testBindingPattern(t *testing.T, p ast.Pattern, name string) bool {
+	binding, isBinding := p.(*ast.BindingPattern)
+	if !isBinding {
+		t.Errorf("p is not *ast.BindingPattern. got=%T", p)
+		return false
+	}
+	return testIdentifier(t, binding.Name, name)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b, ...rest] = xs;", "let [a, b, ...rest] = xs;"},
+		{"let {name, age} = user", "let {name, age} = user;"},
+		{`let {"first name": first, tags: [tag]} = user`, `let {"first name": first, "tags": [tag]} = user;`},
+		{"let [] = xs", "let [] = xs;"},
+		{"fn([x, y], {z}, w) { x }", "fn([x, y], {z}, w) x"},
+		{"fn(_, x) { x }", "fn(_, x) x"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{"let [a, 1 + 2] = xs", "let {a b} = x", "fn(1) { 1 }", "fn([a, ...b, c]) { 1 }", "let 5 = x"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
 		}
 	}
 }
@@ -490,6 +789,40 @@ func TestCallArgs(t *testing.T) {
 	}
 }
 
+func TestTryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { x } catch (e) { y }", "try x catch (e) y"},
+		{"try { x } finally { z }", "try x finally z"},
+		{"try { x } catch (e) { y } finally { z }", "try x catch (e) y finally z"},
+		{"throw x + 1;", "throw (x + 1);"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestTryWithoutHandler(t *testing.T) {
+	p := New(lexer.New("try { x }"))
+	p.Parse()
+
+	if len(p.Errs()) == 0 {
+		t.Fatalf("expected parser errors for try without catch or finally")
+	}
+}
+
 func testIdentifier(t *testing.T, expr ast.Expression, value string) bool {
 	ident, isIdent := expr.(*ast.Identifier)
 	if !isIdent {
@@ -580,6 +913,121 @@ func testInfixExpression(t *testing.T, expr ast.Expression, left any, op string,
 	return true
 }
 
+func TestImportExportStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/strings" as s;`, `import "lib/strings" as s;`},
+		{`import "lib/strings.bl"`, `import "lib/strings.bl" as strings;`},
+		{`export let x = 5;`, `export let x = 5;`},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`import "lib/my-strings"`, `import lib`, `import "x" as 1`, `export 5`} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestStructStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y }", "struct Point { x, y }"},
+		{"struct Point { x, y, };", "struct Point { x, y }"},
+		{"struct Unit {}", "struct Unit {}"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if _, isStruct := program.Statements[0].(*ast.StructStatement); !isStruct {
+			t.Fatalf("program.Statements[0] is not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{"struct Point { x, x }", "struct { x }", "struct Point { x y }", "x = 1", "f() = 1", "p?.[0] = 1"} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 0 => "zero", -1 => "minus", _ => "other" }`, `match (x) { 0 => zero, (-1) => minus, _ => other }`},
+		{`match (x) { n if n > 0 => n, "a" => { 1 } true => 2 }`, `match (x) { n if (n > 0) => n, "a" => 1, true => 2 }`},
+		{`match (xs) { [] => 0, [first, ...rest] => first, [a, [b, _],] => b }`, `match (xs) { [] => 0, [first, ...rest] => first, [a, [b, _]] => b }`},
+		{`match (u) { {"type": "user", "id": id} => id, {name, age: [a]} => name, }`, `match (u) { {"type": "user", id} => id, {name, "age": [a]} => name }`},
+		{`match (x) {}`, `match (x) {  }`},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, isMatch := stmt.Expression.(*ast.MatchExpression); !isMatch {
+			t.Fatalf("stmt.Expression is not *ast.MatchExpression. got=%T", stmt.Expression)
+		}
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{
+		"match x { _ => 1 }",
+		"match (x) { 1 + 2 => 1 }",
+		"match (x) { 1 => 1 2 => 2 }",
+		"match (x) { [...a, b] => 1 }",
+		"match (x) { {1: a} => 1 }",
+		"match (x) { a }",
+		"match (x) { -y => 1 }",
+		"match (x) { -f(1) => 1 }",
+		"match (x) { --1 => 1 }",
+	} {
+		p := New(lexer.New(input))
+		p.Parse()
+
+		if len(p.Errs()) == 0 {
+			t.Errorf("expected parser errors for %s", input)
+		}
+	}
+}
+
 func TestPrefixExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -796,6 +1244,86 @@ func TestOperatorPrecedences(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a + f(b)?",
+			"(a + (f(b)?))",
+		},
+		{
+			"-a?",
+			"(-(a?))",
+		},
+		{
+			"a[0]? * 2",
+			"(((a[0])?) * 2)",
+		},
+		{
+			"xs[1:][0] + a[:b * 2]",
+			"(((xs[1:])[0]) + (a[:(b * 2)]))",
+		},
+		{
+			"a.b.c(1)[2] + -x.len()",
+			"((((a.b).c)(1)[2]) + (-(x.len)()))",
+		},
+		{
+			"p.x = q.y = 1 + 2",
+			"((p.x) = ((q.y) = (1 + 2)))",
+		},
+		{
+			"xs |> f(1) |> g()",
+			"g(f(xs, 1))",
+		},
+		{
+			"a + b |> f",
+			"f((a + b))",
+		},
+		{
+			"xs |> len() > 1",
+			"(len(xs) > 1)",
+		},
+		{
+			"xs |> (f(1)) |> (f)(2)",
+			"f(f(1)(xs), 2)",
+		},
+		{
+			"x => x + 1",
+			"fn(x) (x + 1)",
+		},
+		{
+			"map(xs, (a, b) => a * b)",
+			"map(xs, fn(a, b) (a * b))",
+		},
+		{
+			"a == b ? x + 1 : y",
+			"((a == b) ? (x + 1) : y)",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ?? b == c",
+			"(a ?? (b == c))",
+		},
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			"x ?? y ? 1 : 2",
+			"((x ?? y) ? 1 : 2)",
+		},
+		{
+			"a?.b.c?.[1]",
+			"(((a?.b).c)?.[1])",
+		},
+		{
+			"f(x)? * 2",
+			"((f(x)?) * 2)",
+		},
+		{
+			"f(x)? - 1",
+			"((f(x)?) - 1)",
+		},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/nayyara-airlangga/basedlang/ast"
+	"github.com/nayyara-airlangga/basedlang/token"
+)
+
+// parsePattern parses the pattern starting at the current token.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curTok.Type {
+	case token.IDENT:
+		if p.curTok.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curTok}
+		}
+		return &ast.BindingPattern{Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+	case token.MINUS:
+		// Only negative number literals, the rest of the expression grammar
+		// has no place in a pattern
+		minus := p.curTok
+		if !p.expectPeek(token.INT) {
+			return nil
+		}
+		value := p.parseIntLiteral()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: &ast.PrefixExpression{Token: minus, Operator: minus.Literal, Right: value}}
+	case token.INT, token.STRING, token.TRUE, token.FALSE:
+		value := p.prefixParseFns[p.curTok.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Value: value}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		msg := fmt.Sprintf("invalid pattern starting with %s", p.curTok.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curTok}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			if pattern.Rest = p.parsePattern(); pattern.Rest == nil {
+				return nil
+			}
+			if p.peekTokenIs(token.COMMA) {
+				p.nextToken()
+			}
+			break
+		}
+
+		elem := p.parsePattern()
+		if elem == nil {
+			return nil
+		}
+		pattern.Elems = append(pattern.Elems, elem)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parseHashPattern parses {"key": pattern, name}, where name is short for
+// "name": name.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curTok}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		pair := &ast.HashPatternPair{Key: p.curTok.Literal}
+		switch {
+		case p.curTokenIs(token.IDENT) && !p.peekTokenIs(token.COLON):
+			pair.Value = &ast.BindingPattern{Name: &ast.Identifier{Token: p.curTok, Value: p.curTok.Literal}}
+		case p.curTokenIs(token.IDENT) || p.curTokenIs(token.STRING):
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			if pair.Value = p.parsePattern(); pair.Value == nil {
+				return nil
+			}
+		default:
+			msg := fmt.Sprintf("invalid hash pattern key %s", p.curTok.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return pattern
+}
+
+// parseMatchExpression parses match (subject) { pattern if guard => body, ... }
+// where the guard is optional and the body is an expression or a block.
+// Commas are required after expression bodies, except after the last arm.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expr := &ast.MatchExpression{Token: p.curTok}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expr.Subject = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return nil
+		}
+
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			p.nextToken()
+			noArrow := p.noArrow
+			p.noArrow = true
+			arm.Guard = p.parseExpression(LOWEST)
+			p.noArrow = noArrow
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		// Like in Rust, arms with a block body don't need a comma after them
+		if p.curTokenIs(token.LBRACE) {
+			arm.Body = p.parseBlockStatement()
+			if p.peekTokenIs(token.COMMA) {
+				p.nextToken()
+			}
+		} else {
+			arm.Body = p.parseExpression(LOWEST)
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+		}
+		expr.Arms = append(expr.Arms, arm)
+	}
+	p.nextToken()
+
+	return expr
+}
@@ -2,8 +2,10 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/nayyara-airlangga/basedlang/evaluator"
 	"github.com/nayyara-airlangga/basedlang/lexer"
@@ -14,17 +16,22 @@ import (
 const prompt string = ">> "
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	// Programs read their input from the same buffer as the REPL so that
+	// read_line picks up the lines typed after the one being evaluated
+	reader := bufio.NewReader(in)
+	// The REPL user can already reach the whole host, so imports may too
+	modules := evaluator.NewModuleLoader()
+	modules.ReadFile = os.ReadFile
+	opts := evaluator.Options{Stdin: reader, Stdout: out, Modules: modules}
 	env := object.NewEnvironment()
 
 	for {
 		fmt.Fprint(out, prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
 		l := lexer.New(line)
 		p := parser.New(l)
 
@@ -34,7 +41,7 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		if evaluated := evaluator.Eval(program, env); evaluated != nil {
+		if evaluated := evaluator.EvalContext(context.Background(), program, env, opts); evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
 		}
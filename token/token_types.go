@@ -8,6 +8,7 @@ const (
 	IDENT  TokenType = "IDENT" // AKA variable names
 	INT    TokenType = "INT"   // integer numbers
 	STRING TokenType = "STRING"
+	REGEX  TokenType = "REGEX"
 
 	// Operators
 	ASSIGN   TokenType = "="
@@ -16,6 +17,11 @@ const (
 	BANG     TokenType = "!"
 	ASTERISK TokenType = "*"
 	SLASH    TokenType = "/"
+	QUESTION TokenType = "?"
+	TERNARY  TokenType = "TERNARY" // a ? the parser found to be cond ? a : b
+	NULLISH  TokenType = "??"
+	OPTIONAL TokenType = "?."
+	PIPE     TokenType = "|>"
 
 	LT  TokenType = "<"
 	GT  TokenType = ">"
@@ -26,6 +32,10 @@ const (
 
 	// Delimiters
 	COMMA     TokenType = ","
+	COLON     TokenType = ":"
+	DOT       TokenType = "."
+	ELLIPSIS  TokenType = "..."
+	ARROW     TokenType = "=>"
 	SEMICOLON TokenType = ";"
 
 	LPAREN   TokenType = "("
@@ -38,9 +48,19 @@ const (
 	// Keywords
 	FUNCTION TokenType = "FUNCTION"
 	LET      TokenType = "LET"
+	CONST    TokenType = "CONST"
 	TRUE     TokenType = "TRUE"
 	FALSE    TokenType = "FALSE"
 	IF       TokenType = "IF"
 	ELSE     TokenType = "ELSE"
 	RETURN   TokenType = "RETURN"
+	THROW    TokenType = "THROW"
+	TRY      TokenType = "TRY"
+	CATCH    TokenType = "CATCH"
+	FINALLY  TokenType = "FINALLY"
+	IMPORT   TokenType = "IMPORT"
+	EXPORT   TokenType = "EXPORT"
+	AS       TokenType = "AS"
+	STRUCT   TokenType = "STRUCT"
+	MATCH    TokenType = "MATCH"
 )
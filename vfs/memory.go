@@ -0,0 +1,131 @@
+package vfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"testing/fstest"
+)
+
+// Memory is an in-memory FS rooted at "/". It is safe for concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// NewMemory returns an empty in-memory filesystem.
+func NewMemory() *Memory {
+	return &Memory{files: fstest.MapFS{}}
+}
+
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	name, err := cleanRel("read", name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.files.ReadFile(name)
+}
+
+func (m *Memory) WriteFile(name string, data []byte) error {
+	cleaned, err := cleanRel("write", name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if info, err := m.files.Stat(path.Dir(cleaned)); err != nil || !info.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+	}
+	if file, exists := m.files[cleaned]; exists && file.Mode.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+
+	m.files[cleaned] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: 0o644}
+	return nil
+}
+
+func (m *Memory) ReadDir(name string) ([]fs.DirEntry, error) {
+	name, err := cleanRel("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.files.ReadDir(name)
+}
+
+func (m *Memory) Stat(name string) (fs.FileInfo, error) {
+	name, err := cleanRel("stat", name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.files.Stat(name)
+}
+
+func (m *Memory) MkdirAll(name string) error {
+	cleaned, err := cleanRel("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for dir := cleaned; dir != "."; dir = path.Dir(dir) {
+		if file, exists := m.files[dir]; exists && !file.Mode.IsDir() {
+			return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrExist}
+		}
+	}
+	if cleaned != "." {
+		m.files[cleaned] = &fstest.MapFile{Mode: fs.ModeDir | 0o755}
+	}
+	return nil
+}
+
+func (m *Memory) Remove(name string) error {
+	cleaned, err := cleanRel("remove", name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	info, err := m.files.Stat(cleaned)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		if cleaned == "." || m.hasChildren(cleaned) {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+		}
+	}
+
+	delete(m.files, cleaned)
+	return nil
+}
+
+// hasChildren reports whether anything is stored below dir. Directories that
+// exist only because of their contents have no entry of their own.
+func (m *Memory) hasChildren(dir string) bool {
+	prefix := dir + "/"
+	for name := range m.files {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
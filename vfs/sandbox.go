@@ -0,0 +1,169 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sandbox is an FS over the host's filesystem that only reaches into an
+// allowlist of root directories.
+type sandbox struct {
+	roots []string // absolute, with symlinks resolved
+}
+
+// Sandbox returns an FS limited to the given root directories. Relative names
+// are resolved against the first root and absolute names must lie in one of
+// them. Names that leave the roots, through ".." or through a symlink, fail
+// with ErrOutsideRoot.
+func Sandbox(roots ...string) (FS, error) {
+	if len(roots) == 0 {
+		return nil, errors.New("sandbox needs at least one root")
+	}
+
+	s := &sandbox{}
+	for _, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, err
+		}
+		real, err := filepath.EvalSymlinks(abs)
+		if err != nil {
+			return nil, err
+		}
+		s.roots = append(s.roots, real)
+	}
+	return s, nil
+}
+
+// resolve maps name to a host path inside one of the roots.
+func (s *sandbox) resolve(op, name string) (string, error) {
+	p := filepath.FromSlash(name)
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.roots[0], p)
+	}
+	p = filepath.Clean(p)
+
+	real, err := evalExistingSymlinks(p)
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if !s.contains(p) || !s.contains(real) {
+		return "", &fs.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+	}
+	return real, nil
+}
+
+func (s *sandbox) contains(p string) bool {
+	for _, root := range s.roots {
+		if rel, err := filepath.Rel(root, p); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evalExistingSymlinks resolves the symlinks in the longest existing prefix
+// of p, so that paths about to be created can be checked too. A dangling
+// symlink is resolved to the path it points to, since creating the file
+// would follow it.
+func evalExistingSymlinks(p string) (string, error) {
+	real, err := filepath.EvalSymlinks(p)
+	if err == nil {
+		return real, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	parent := filepath.Dir(p)
+	if parent == p {
+		return p, nil
+	}
+	realParent, err := evalExistingSymlinks(parent)
+	if err != nil {
+		return "", err
+	}
+	resolved := filepath.Join(realParent, filepath.Base(p))
+
+	info, err := os.Lstat(resolved)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return resolved, nil
+	}
+	target, err := os.Readlink(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(realParent, target)
+	}
+	return evalExistingSymlinks(filepath.Clean(target))
+}
+
+// withName reports errors under the name the script used rather than the host
+// path.
+func withName(err error, name string) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
+
+func (s *sandbox) ReadFile(name string) ([]byte, error) {
+	p, err := s.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	return data, withName(err, name)
+}
+
+func (s *sandbox) WriteFile(name string, data []byte) error {
+	p, err := s.resolve("write", name)
+	if err != nil {
+		return err
+	}
+	return withName(os.WriteFile(p, data, 0o644), name)
+}
+
+func (s *sandbox) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := s.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(p)
+	return entries, withName(err, name)
+}
+
+func (s *sandbox) Stat(name string) (fs.FileInfo, error) {
+	p, err := s.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	return info, withName(err, name)
+}
+
+func (s *sandbox) MkdirAll(name string) error {
+	p, err := s.resolve("mkdir", name)
+	if err != nil {
+		return err
+	}
+	return withName(os.MkdirAll(p, 0o755), name)
+}
+
+func (s *sandbox) Remove(name string) error {
+	p, err := s.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	for _, root := range s.roots {
+		if p == root {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+		}
+	}
+	return withName(os.Remove(p), name)
+}
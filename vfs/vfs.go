@@ -0,0 +1,37 @@
+// Package vfs provides the filesystems that the fs builtins operate on. A host
+// picks one when it sets up an evaluation: Sandbox for real directories, or
+// Memory for tests.
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ErrOutsideRoot is returned for paths that leave the filesystem's roots.
+var ErrOutsideRoot = errors.New("path escapes the allowed roots")
+
+// FS is a filesystem that scripts can read and write. Names are slash
+// separated.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	// MkdirAll creates a directory along with any missing parents.
+	MkdirAll(name string) error
+	// Remove removes a file or an empty directory.
+	Remove(name string) error
+}
+
+// cleanRel cleans a name relative to a root, rejecting names that climb out
+// of it with "..". A leading slash refers to the root itself.
+func cleanRel(op, name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "/"))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", &fs.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,125 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testFS(t *testing.T, fsys FS) {
+	t.Helper()
+
+	if err := fsys.MkdirAll("a/b"); err != nil {
+		t.Fatalf("MkdirAll returned error: %v", err)
+	}
+	if err := fsys.WriteFile("a/b/f.txt", []byte("hi")); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := fsys.WriteFile("a/g.txt", []byte("yo")); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	data, err := fsys.ReadFile("a/b/../b/f.txt")
+	if err != nil || string(data) != "hi" {
+		t.Errorf("ReadFile wrong. got=%q, %v", data, err)
+	}
+
+	entries, err := fsys.ReadDir("a")
+	if err != nil {
+		t.Fatalf("ReadDir returned error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "b" || !entries[0].IsDir() || entries[1].Name() != "g.txt" {
+		t.Errorf("ReadDir wrong. got=%v", entries)
+	}
+
+	if err := fsys.WriteFile("missing/f.txt", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("WriteFile into a missing directory should fail with ErrNotExist. got=%v", err)
+	}
+	if err := fsys.Remove("a/b"); err == nil {
+		t.Errorf("Remove of a non-empty directory should fail")
+	}
+	if err := fsys.Remove("a/b/f.txt"); err != nil {
+		t.Errorf("Remove returned error: %v", err)
+	}
+	if _, err := fsys.Stat("a/b/f.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat of a removed file should fail with ErrNotExist. got=%v", err)
+	}
+	if err := fsys.Remove("a/b"); err != nil {
+		t.Errorf("Remove of an empty directory returned error: %v", err)
+	}
+
+	for _, name := range []string{"..", "../x", "a/../../x", "/../x"} {
+		if _, err := fsys.ReadFile(name); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("ReadFile(%q) should fail with ErrOutsideRoot. got=%v", name, err)
+		}
+		if err := fsys.WriteFile(name, nil); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("WriteFile(%q) should fail with ErrOutsideRoot. got=%v", name, err)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testFS(t, NewMemory())
+}
+
+func TestSandbox(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := Sandbox(root)
+	if err != nil {
+		t.Fatalf("Sandbox returned error: %v", err)
+	}
+	testFS(t, fsys)
+
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("s"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if _, err := fsys.ReadFile("link/secret"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("reading through a symlink out of the root should fail. got=%v", err)
+	}
+	if err := fsys.WriteFile("link/new", nil); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("writing through a symlink out of the root should fail. got=%v", err)
+	}
+	if _, err := fsys.ReadFile(filepath.Join(outside, "secret")); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("reading an absolute path out of the root should fail. got=%v", err)
+	}
+
+	if err := os.Symlink(filepath.Join(outside, "planted"), filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("dangling", []byte("x")); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("writing through a dangling symlink out of the root should fail. got=%v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "planted")); err == nil {
+		t.Errorf("writing through a dangling symlink created a file out of the root")
+	}
+
+	if err := os.Symlink("a/created.txt", filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("inside", []byte("in")); err != nil {
+		t.Errorf("writing through a dangling symlink in the root returned error: %v", err)
+	}
+	if data, err := fsys.ReadFile("a/created.txt"); err != nil || string(data) != "in" {
+		t.Errorf("writing through a dangling symlink in the root wrong. got=%q, %v", data, err)
+	}
+
+	data, err := fsys.ReadFile(filepath.Join(root, "a", "g.txt"))
+	if err != nil || string(data) != "yo" {
+		t.Errorf("reading an absolute path in the root wrong. got=%q, %v", data, err)
+	}
+
+	multi, err := Sandbox(root, outside)
+	if err != nil {
+		t.Fatalf("Sandbox returned error: %v", err)
+	}
+	if _, err := multi.ReadFile(filepath.Join(outside, "secret")); err != nil {
+		t.Errorf("reading from the second root returned error: %v", err)
+	}
+}